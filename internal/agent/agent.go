@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultIdleTimeout is how long the agent holds a key that is not used
+const DefaultIdleTimeout = 15 * time.Minute
+
+// requestTimeout bounds a single exchange with the agent
+const requestTimeout = 10 * time.Second
+
+var (
+	ErrNotRunning     = errors.New("agent is not running")
+	ErrAlreadyRunning = errors.New("agent is already running")
+	ErrUnsupported    = errors.New("the agent is not supported on this platform")
+)
+
+// Operations understood by the agent
+const (
+	opGet  = "get"  // return the key held for a vault
+	opAdd  = "add"  // hold a key for a vault
+	opLock = "lock" // forget the key of one vault, or of all
+	opList = "list" // list the vaults held
+	opStop = "stop" // forget all keys and exit
+)
+
+// request is sent by a client, one per connection
+type request struct {
+	Op          string `json:"op"`
+	Vault       string `json:"vault,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Key         []byte `json:"key,omitempty"`
+}
+
+// response is the agent's answer to a request
+type response struct {
+	Error  string      `json:"error,omitempty"`
+	Key    []byte      `json:"key,omitempty"`
+	Count  int         `json:"count,omitempty"`
+	Vaults []VaultInfo `json:"vaults,omitempty"`
+}
+
+// VaultInfo describes a vault whose key the agent holds
+type VaultInfo struct {
+	Path      string    `json:"path"`
+	LastUsed  time.Time `json:"last_used"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // zero if the key never expires
+}
+
+// SocketPath returns the path of the agent's socket
+// $VAULTIX_AGENT_SOCK overrides it; otherwise the socket is kept in
+// $XDG_RUNTIME_DIR, or in a private directory in the system temp directory.
+func SocketPath() string {
+	if path := os.Getenv("VAULTIX_AGENT_SOCK"); path != "" {
+		return path
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "vaultix-agent.sock")
+	}
+	return filepath.Join(os.TempDir(), "vaultix-"+strconv.Itoa(os.Getuid()), "agent.sock")
+}
+
+// Client talks to the agent listening on a socket
+type Client struct {
+	socketPath string
+}
+
+// NewClient returns a client for the agent at socketPath
+func NewClient(socketPath string) *Client {
+	return &Client{socketPath: socketPath}
+}
+
+// Key returns the key the agent holds for a vault, or nil if it holds none
+// The key is only returned if fingerprint matches the one it was added with,
+// so a vault recreated or given a new password is not opened with a stale key.
+func (c *Client) Key(vaultPath, fingerprint string) ([]byte, error) {
+	resp, err := c.call(request{Op: opGet, Vault: vaultPath, Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// Add gives the agent the unlocked key of a vault to hold
+func (c *Client) Add(vaultPath, fingerprint string, key []byte) error {
+	_, err := c.call(request{Op: opAdd, Vault: vaultPath, Fingerprint: fingerprint, Key: key})
+	return err
+}
+
+// Lock makes the agent forget the key of a vault, or of every vault if
+// vaultPath is ""
+// Returns the number of keys forgotten.
+func (c *Client) Lock(vaultPath string) (int, error) {
+	resp, err := c.call(request{Op: opLock, Vault: vaultPath})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// List returns the vaults whose keys the agent holds, sorted by path
+func (c *Client) List() ([]VaultInfo, error) {
+	resp, err := c.call(request{Op: opList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Vaults, nil
+}
+
+// Stop makes the agent forget all keys and exit
+func (c *Client) Stop() error {
+	_, err := c.call(request{Op: opStop})
+	return err
+}
+
+// call sends a request and reads the response
+// Returns ErrNotRunning if nothing is listening on the socket.
+func (c *Client) call(req request) (*response, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, requestTimeout)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	// Only talk to an agent run by the same user; another user's process
+	// could otherwise collect keys through a planted socket
+	if err := checkPeer(conn); err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response from agent: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// checkPeer rejects a connection whose other end belongs to another user
+func checkPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("agent connection is not a Unix socket")
+	}
+	uid, err := peerUID(unixConn)
+	if err != nil {
+		return fmt.Errorf("failed to check agent peer: %w", err)
+	}
+	if uid != os.Getuid() {
+		return fmt.Errorf("agent socket peer belongs to uid %d, not %d", uid, os.Getuid())
+	}
+	return nil
+}
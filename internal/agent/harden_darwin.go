@@ -0,0 +1,11 @@
+package agent
+
+import "golang.org/x/sys/unix"
+
+// hardenProcess stops the agent's memory from being read through core dumps
+func hardenProcess() {
+	unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0})
+}
+
+// excludeFromDump does nothing here; core dumps are disabled by hardenProcess
+func excludeFromDump(mem []byte) {}
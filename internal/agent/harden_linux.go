@@ -0,0 +1,15 @@
+package agent
+
+import "golang.org/x/sys/unix"
+
+// hardenProcess stops the agent's memory from being read through core dumps
+// or, by other processes of the same user, through ptrace and /proc
+func hardenProcess() {
+	unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0})
+	unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0)
+}
+
+// excludeFromDump leaves a memory mapping out of core dumps
+func excludeFromDump(mem []byte) {
+	unix.Madvise(mem, unix.MADV_DONTDUMP)
+}
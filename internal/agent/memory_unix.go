@@ -0,0 +1,53 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockedKey is a key kept in memory that is never swapped out or written to
+// a core dump
+type lockedKey struct {
+	mem []byte // the whole mapping, one page
+	key []byte // the key, at the start of mem
+}
+
+// newLockedKey copies key into locked memory
+func newLockedKey(key []byte) (*lockedKey, error) {
+	size := unix.Getpagesize()
+	if len(key) > size {
+		return nil, fmt.Errorf("key too long: %d bytes", len(key))
+	}
+
+	mem, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate key memory: %w", err)
+	}
+	if err := unix.Mlock(mem); err != nil {
+		unix.Munmap(mem)
+		return nil, fmt.Errorf("failed to lock key memory: %w", err)
+	}
+	excludeFromDump(mem)
+
+	k := &lockedKey{mem: mem, key: mem[:len(key)]}
+	copy(k.key, key)
+	return k, nil
+}
+
+// bytes returns a copy of the key
+func (k *lockedKey) bytes() []byte {
+	return append([]byte(nil), k.key...)
+}
+
+// wipe zeroes the key and releases its memory
+func (k *lockedKey) wipe() {
+	for i := range k.mem {
+		k.mem[i] = 0
+	}
+	unix.Munlock(k.mem)
+	unix.Munmap(k.mem)
+	k.mem, k.key = nil, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package agent
+
+import "net"
+
+// peerUID is not supported on this platform
+func peerUID(conn *net.UnixConn) (int, error) {
+	return 0, ErrUnsupported
+}
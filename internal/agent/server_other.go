@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package agent
+
+import "time"
+
+// Serve is not supported on this platform
+func Serve(socketPath string, idleTimeout time.Duration, ready func()) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,241 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// heldKey is a vault key held by the agent
+type heldKey struct {
+	key         *lockedKey
+	fingerprint string
+	lastUsed    time.Time
+	timer       *time.Timer // forgets the key once idle; nil without a timeout
+}
+
+// server holds unlocked vault keys for clients of the same user
+type server struct {
+	idleTimeout time.Duration
+	listener    net.Listener
+	stopOnce    sync.Once
+
+	mu   sync.Mutex
+	keys map[string]*heldKey // by absolute vault path
+}
+
+// Serve runs the agent on socketPath until it is stopped or receives an
+// interrupt or termination signal
+// Keys not used for idleTimeout are forgotten (0 keeps them until locked).
+// ready, if not nil, is called once the socket accepts connections. All keys
+// are wiped and the socket removed before Serve returns.
+func Serve(socketPath string, idleTimeout time.Duration, ready func()) error {
+	hardenProcess()
+
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// A socket nobody answers on is left over from an agent that died
+	if _, err := os.Lstat(socketPath); err == nil {
+		if _, err := NewClient(socketPath).List(); err == nil {
+			return ErrAlreadyRunning
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	// Create the socket readable and writable only by the owner
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+
+	s := &server{
+		idleTimeout: idleTimeout,
+		listener:    listener,
+		keys:        make(map[string]*heldKey),
+	}
+	defer s.lockAll()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			s.stop()
+		}
+	}()
+
+	if ready != nil {
+		ready()
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go s.handle(conn)
+	}
+}
+
+// stop closes the listener, ending Serve
+func (s *server) stop() {
+	s.stopOnce.Do(func() { s.listener.Close() })
+}
+
+// handle answers one request
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	// Only the user running the agent may use it
+	if err := checkPeer(conn); err != nil {
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := s.serve(&req)
+	json.NewEncoder(conn).Encode(resp)
+	wipeBytes(resp.Key)
+
+	if req.Op == opStop {
+		s.stop()
+	}
+}
+
+// serve carries out a request
+func (s *server) serve(req *request) *response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Op {
+	case opGet:
+		held := s.keys[req.Vault]
+		if held == nil {
+			return &response{}
+		}
+		if held.fingerprint != req.Fingerprint {
+			// The vault was recreated or its password changed
+			s.forget(req.Vault)
+			return &response{}
+		}
+		held.lastUsed = time.Now()
+		if held.timer != nil {
+			held.timer.Reset(s.idleTimeout)
+		}
+		return &response{Key: held.key.bytes()}
+
+	case opAdd:
+		defer wipeBytes(req.Key)
+		if req.Vault == "" || len(req.Key) == 0 {
+			return &response{Error: "vault and key are required"}
+		}
+		key, err := newLockedKey(req.Key)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		s.forget(req.Vault)
+		held := &heldKey{key: key, fingerprint: req.Fingerprint, lastUsed: time.Now()}
+		if s.idleTimeout > 0 {
+			vaultPath := req.Vault
+			held.timer = time.AfterFunc(s.idleTimeout, func() { s.expire(vaultPath, held) })
+		}
+		s.keys[req.Vault] = held
+		return &response{}
+
+	case opLock:
+		if req.Vault == "" {
+			count := len(s.keys)
+			for vaultPath := range s.keys {
+				s.forget(vaultPath)
+			}
+			return &response{Count: count}
+		}
+		if s.keys[req.Vault] == nil {
+			return &response{}
+		}
+		s.forget(req.Vault)
+		return &response{Count: 1}
+
+	case opList:
+		vaults := make([]VaultInfo, 0, len(s.keys))
+		for vaultPath, held := range s.keys {
+			info := VaultInfo{Path: vaultPath, LastUsed: held.lastUsed}
+			if s.idleTimeout > 0 {
+				info.ExpiresAt = held.lastUsed.Add(s.idleTimeout)
+			}
+			vaults = append(vaults, info)
+		}
+		sort.Slice(vaults, func(i, j int) bool { return vaults[i].Path < vaults[j].Path })
+		return &response{Vaults: vaults}
+
+	case opStop:
+		return &response{}
+	}
+
+	return &response{Error: fmt.Sprintf("unknown operation: %s", req.Op)}
+}
+
+// expire forgets a key once it has been idle for the timeout
+func (s *server) expire(vaultPath string, held *heldKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The key may have been replaced, or used while the timer fired
+	if s.keys[vaultPath] != held || time.Since(held.lastUsed) < s.idleTimeout {
+		return
+	}
+	s.forget(vaultPath)
+}
+
+// forget wipes and drops the key of a vault; s.mu must be held
+func (s *server) forget(vaultPath string) {
+	held := s.keys[vaultPath]
+	if held == nil {
+		return
+	}
+	if held.timer != nil {
+		held.timer.Stop()
+	}
+	held.key.wipe()
+	delete(s.keys, vaultPath)
+}
+
+// lockAll wipes every key
+func (s *server) lockAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for vaultPath := range s.keys {
+		s.forget(vaultPath)
+	}
+}
+
+// wipeBytes zeroes a buffer that held a key
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
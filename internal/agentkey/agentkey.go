@@ -0,0 +1,15 @@
+// Package agentkey hands master keys between vaultix sessions and the agent
+// The public vaultix package never exports a master key. It registers these
+// functions instead, so only the CLI, which talks to the agent, can move keys
+// in and out of a session.
+package agentkey
+
+var (
+	// Export returns a copy of the master key of a *vaultix.Session
+	Export func(session any) ([]byte, error)
+
+	// Unlock unlocks a *vaultix.Vault with a master key held by the agent
+	// and returns a *vaultix.Session. The key is checked against the vault's
+	// metadata; a wrong key gives vaultix.ErrIncorrectPassword.
+	Unlock func(v any, key []byte) (any, error)
+)
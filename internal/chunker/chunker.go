@@ -0,0 +1,82 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const (
+	// Chunk size bounds - content-defined boundaries are only considered
+	// between MinSize and MaxSize, averaging roughly AvgSize
+	MinSize = 64 * 1024   // 64 KB
+	AvgSize = 256 * 1024  // 256 KB
+	MaxSize = 1024 * 1024 // 1 MB
+
+	// Boundary when the low bits of the rolling hash are all zero.
+	// Before AvgSize a stricter mask is used, after it a looser one
+	// (normalized chunking), which keeps chunk sizes close to AvgSize.
+	maskStrict = (1 << 20) - 1
+	maskLoose  = (1 << 16) - 1
+)
+
+// Chunker splits data into content-defined chunks using a gear rolling hash
+type Chunker struct {
+	gear [256]uint64
+}
+
+// New creates a chunker whose gear table is derived from the given seed
+// Using a secret seed keeps chunk boundaries (and therefore sizes) unpredictable
+func New(seed []byte) *Chunker {
+	c := &Chunker{}
+	var counter [4]byte
+	for i := 0; i < len(c.gear); i += 4 {
+		// Expand the seed into the table, 4 entries per SHA-256 block
+		binary.BigEndian.PutUint32(counter[:], uint32(i))
+		block := sha256.Sum256(append(append([]byte{}, seed...), counter[:]...))
+		for j := 0; j < 4; j++ {
+			c.gear[i+j] = binary.BigEndian.Uint64(block[j*8:])
+		}
+	}
+	return c
+}
+
+// Split divides data into content-defined chunks
+// The returned slices share memory with data. Empty input yields no chunks.
+func (c *Chunker) Split(data []byte) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := c.nextBoundary(data)
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
+
+// nextBoundary returns the length of the first chunk in data
+func (c *Chunker) nextBoundary(data []byte) int {
+	if len(data) <= MinSize {
+		return len(data)
+	}
+
+	limit := len(data)
+	if limit > MaxSize {
+		limit = MaxSize
+	}
+
+	var hash uint64
+	i := MinSize
+	for ; i < limit && i < AvgSize; i++ {
+		hash = (hash << 1) + c.gear[data[i]]
+		if hash&maskStrict == 0 {
+			return i + 1
+		}
+	}
+	for ; i < limit; i++ {
+		hash = (hash << 1) + c.gear[data[i]]
+		if hash&maskLoose == 0 {
+			return i + 1
+		}
+	}
+
+	return limit
+}
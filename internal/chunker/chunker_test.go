@@ -0,0 +1,108 @@
+package chunker
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// randomData returns n bytes that are the same for the same seed
+func randomData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// lengths returns the length of each chunk
+func lengths(chunks [][]byte) []int {
+	var n []int
+	for _, chunk := range chunks {
+		n = append(n, len(chunk))
+	}
+	return n
+}
+
+func TestSplitDeterministic(t *testing.T) {
+	data := randomData(1, 4*MaxSize)
+
+	first := New([]byte("seed")).Split(data)
+	second := New([]byte("seed")).Split(data)
+	if len(first) < 2 {
+		t.Fatalf("Split gave %d chunks, want several", len(first))
+	}
+	if !equalLengths(lengths(first), lengths(second)) {
+		t.Errorf("same seed gave boundaries %v and %v", lengths(first), lengths(second))
+	}
+
+	other := New([]byte("other seed")).Split(data)
+	if equalLengths(lengths(first), lengths(other)) {
+		t.Errorf("different seeds gave the same boundaries %v", lengths(first))
+	}
+}
+
+func TestSplitSizeLimits(t *testing.T) {
+	c := New([]byte("seed"))
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"random", randomData(2, 5*MaxSize+123)},
+		{"zeros", make([]byte, 3*MaxSize+5)},
+		{"repeating", bytes.Repeat([]byte("abcdefgh"), MaxSize/2)},
+	}
+	for _, tt := range tests {
+		chunks := c.Split(tt.data)
+		for i, chunk := range chunks {
+			if len(chunk) > MaxSize {
+				t.Errorf("%s: chunk %d is %d bytes, more than MaxSize", tt.name, i, len(chunk))
+			}
+			if i < len(chunks)-1 && len(chunk) <= MinSize {
+				t.Errorf("%s: chunk %d is %d bytes, not more than MinSize", tt.name, i, len(chunk))
+			}
+		}
+		if joined := bytes.Join(chunks, nil); !bytes.Equal(joined, tt.data) {
+			t.Errorf("%s: chunks do not add up to the data", tt.name)
+		}
+	}
+
+	if chunks := c.Split(nil); len(chunks) != 0 {
+		t.Errorf("Split(nil) gave %d chunks, want none", len(chunks))
+	}
+	small := randomData(3, MinSize)
+	if chunks := c.Split(small); len(chunks) != 1 || len(chunks[0]) != MinSize {
+		t.Errorf("Split of MinSize bytes gave %v, want one chunk", lengths(chunks))
+	}
+}
+
+func TestSplitRealignsAfterInsert(t *testing.T) {
+	c := New([]byte("seed"))
+	data := randomData(4, 6*MaxSize)
+	edited := append([]byte("inserted at the start"), data...)
+
+	// Content-defined boundaries resynchronise, so later chunks are shared
+	before := map[string]bool{}
+	for _, chunk := range c.Split(data) {
+		before[string(chunk)] = true
+	}
+	shared := 0
+	for _, chunk := range c.Split(edited) {
+		if before[string(chunk)] {
+			shared++
+		}
+	}
+	if shared < len(before)/2 {
+		t.Errorf("only %d of %d chunks survive an insert at the start", shared, len(before))
+	}
+}
+
+func equalLengths(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/agent"
+	"github.com/Zayan-Mohamed/vaultix/internal/agentkey"
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// agentStartTimeout bounds how long agent start waits for the agent to listen
+const agentStartTimeout = 5 * time.Second
+
+// unlockVault unlocks the vault at absVaultPath, reading its password with
+// prompt
+// When the agent is running, a key it holds for the vault is used without
+// asking for the password; otherwise the key unlocked by the password is
+// handed to the agent for later commands. Setting $VAULTIX_NO_AGENT bypasses
+// the agent. The caller closes the session.
+func unlockVault(absVaultPath string, prompt func(string) (string, error)) (*vaultix.Session, error) {
+	v, err := vaultix.Open(absVaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	client, fingerprint := agentFor(v)
+	if client != nil {
+		key, err := client.Key(absVaultPath, fingerprint)
+		if err != nil {
+			if !errors.Is(err, agent.ErrNotRunning) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			client = nil
+		} else if key != nil {
+			session, err := agentkey.Unlock(v, key)
+			clear(key)
+			if err == nil {
+				return session.(*vaultix.Session), nil
+			}
+			// The agent holds a key that no longer opens the vault; ask for
+			// the password and hand the agent the right key
+			fmt.Fprintf(os.Stderr, "Warning: agent key rejected: %v\n", err)
+		}
+	}
+
+	// Read password
+	password, err := prompt("Enter vault password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := v.Unlock(vaultix.Password(password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock vault: %w", err)
+	}
+
+	if client != nil {
+		key, err := agentkey.Export(session)
+		if err == nil {
+			err = client.Add(absVaultPath, fingerprint, key)
+			clear(key)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return session, nil
+}
+
+// agentFor returns a client for the agent and the vault's key fingerprint,
+// or nil if the agent is bypassed or the vault has no password-protected key
+func agentFor(v *vaultix.Vault) (*agent.Client, string) {
+	if os.Getenv("VAULTIX_NO_AGENT") != "" {
+		return nil, ""
+	}
+	fingerprint, err := v.KeyFingerprint()
+	if err != nil {
+		return nil, ""
+	}
+	return agent.NewClient(agent.SocketPath()), fingerprint
+}
+
+// Agent starts, stops or shows the agent holding unlocked vault keys
+func Agent(args []string) error {
+	p, err := parseArgs(args, []string{"timeout"}, []string{"foreground"})
+	if err != nil {
+		return err
+	}
+
+	action := "start"
+	if len(p.positional) >= 1 {
+		action = p.positional[0]
+	}
+
+	socketPath := agent.SocketPath()
+	client := agent.NewClient(socketPath)
+
+	switch action {
+	case "start":
+		timeout := agent.DefaultIdleTimeout
+		if value := p.value("timeout"); value != "" {
+			timeout, err = time.ParseDuration(value)
+			if err != nil || timeout < 0 {
+				return fmt.Errorf("invalid timeout: %s (use a duration such as 30m or 2h, or 0 for none)", value)
+			}
+		}
+		if p.bool("foreground") {
+			return agent.Serve(socketPath, timeout, func() {
+				fmt.Printf("✓ Agent listening on %s (idle timeout: %s)\n", socketPath, formatTimeout(timeout))
+			})
+		}
+		return startAgent(client, socketPath, timeout)
+
+	case "status":
+		vaults, err := client.List()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Agent running on %s\n", socketPath)
+		if len(vaults) == 0 {
+			fmt.Println("No vaults unlocked")
+			return nil
+		}
+		fmt.Printf("Unlocked vaults (%d):\n", len(vaults))
+		for _, info := range vaults {
+			expires := "never expires"
+			if !info.ExpiresAt.IsZero() {
+				expires = fmt.Sprintf("locks at %s", info.ExpiresAt.Format("15:04:05"))
+			}
+			fmt.Printf("  %s (last used: %s, %s)\n", info.Path, info.LastUsed.Format("15:04:05"), expires)
+		}
+		return nil
+
+	case "stop":
+		if err := client.Stop(); err != nil {
+			return err
+		}
+		fmt.Println("✓ Agent stopped (all vaults locked)")
+		return nil
+	}
+
+	return fmt.Errorf("unknown agent command: %s (use start, status or stop)", action)
+}
+
+// startAgent runs the agent in the background and waits until it listens
+func startAgent(client *agent.Client, socketPath string, timeout time.Duration) error {
+	if _, err := client.List(); err == nil {
+		return agent.ErrAlreadyRunning
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find vaultix executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, "agent", "start", "--foreground", "--timeout", timeout.String())
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(agentStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return fmt.Errorf("failed to start agent: %w (run vaultix agent start --foreground to see why)", err)
+		case <-deadline:
+			return fmt.Errorf("agent did not start listening on %s", socketPath)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		if _, err := client.List(); err == nil {
+			fmt.Printf("✓ Agent started on %s (idle timeout: %s)\n", socketPath, formatTimeout(timeout))
+			fmt.Println("  Vaults unlocked from now on stay unlocked until idle or locked (vaultix lock)")
+			return nil
+		}
+	}
+}
+
+// formatTimeout describes an idle timeout, where 0 means none
+func formatTimeout(timeout time.Duration) string {
+	if timeout == 0 {
+		return "none"
+	}
+	return timeout.String()
+}
+
+// Lock makes the agent forget the key of a vault, or of every vault
+func Lock(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, []string{"all"})
+	if err != nil {
+		return err
+	}
+
+	client := agent.NewClient(agent.SocketPath())
+
+	if p.bool("all") {
+		count, err := client.Lock("")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Locked %d vault(s)\n", count)
+		return nil
+	}
+
+	vaultPath := "."
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
+	}
+	if flagPath := p.value("vault"); flagPath != "" {
+		vaultPath = flagPath
+	}
+
+	// The vault need not exist any more, so its path is not checked
+	absVaultPath, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return fmt.Errorf("invalid vault path: %w", err)
+	}
+
+	count, err := client.Lock(absVaultPath)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Printf("Vault was not unlocked: %s\n", absVaultPath)
+		return nil
+	}
+	fmt.Printf("✓ Vault locked: %s\n", absVaultPath)
+	return nil
+}
@@ -4,11 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
-	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
-	"github.com/Zayan-Mohamed/vaultix/internal/storage"
-	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
 	"golang.org/x/term"
 )
 
@@ -25,9 +24,17 @@ func readPassword(prompt string) (string, error) {
 
 // Init initializes a new vault at the specified path
 func Init(args []string) error {
+	p, err := parseArgs(args, append([]string{"vault"}, filterValueFlags...), append([]string{"dry-run"}, filterBoolFlags...))
+	if err != nil {
+		return err
+	}
+
 	vaultPath := "."
-	if len(args) >= 1 {
-		vaultPath = args[0]
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
+	}
+	if flagPath := p.value("vault"); flagPath != "" {
+		vaultPath = flagPath
 	}
 
 	// Convert to absolute path
@@ -37,177 +44,839 @@ func Init(args []string) error {
 	}
 
 	// Check if vault already exists
-	if storage.VaultExists(absPath) {
+	if vaultix.Exists(absPath) {
 		return fmt.Errorf("vault already exists at: %s", absPath)
 	}
 
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(absPath, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
+	if p.bool("dry-run") {
+		plan, err := vaultix.PlanInit(absPath, fileFilter(p))
+		if err != nil {
+			return fmt.Errorf("failed to plan vault initialization: %w", err)
+		}
+		printTreePlan(plan)
+		return nil
+	}
+
+	// Create directory if it doesn't exist (the parent directory for a container file)
+	dirPath := absPath
+	if vaultix.IsContainer(absPath) {
+		dirPath = filepath.Dir(absPath)
+	}
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Read password
+	password, err := readPassword("Enter password: ")
+	if err != nil {
+		return err
+	}
+
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+
+	// Confirm password
+	confirmPassword, err := readPassword("Confirm password: ")
+	if err != nil {
+		return err
+	}
+
+	if password != confirmPassword {
+		return fmt.Errorf("passwords do not match")
+	}
+
+	// Initialize vault
+	spinner := NewProgressSpinner("Encrypting")
+	spinner.Start()
+
+	_, recoveryKey, err := vaultix.Init(absPath, password, &vaultix.InitOptions{
+		Filter: fileFilter(p),
+		Progress: func(current, total int, message string) {
+			spinner.Update(current, total, message)
+		},
+	})
+
+	spinner.Stop()
+	<-spinner.done
+
+	if err != nil {
+		return fmt.Errorf("failed to initialize vault: %w", err)
+	}
+
+	fmt.Printf("✓ Vault initialized at: %s\n", absPath)
+	fmt.Println("✓ All files have been encrypted")
+	fmt.Println("✓ Original plaintext files have been securely deleted")
+	fmt.Println()
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("             IMPORTANT: RECOVERY KEY")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+	fmt.Println("Your recovery key (save this in a secure location):")
+	fmt.Println()
+	fmt.Printf("  %s\n", recoveryKey)
+	fmt.Println()
+	fmt.Println("This recovery key can unlock your vault if you forget your password.")
+	fmt.Println("Store it safely - if you lose both your password AND recovery key,")
+	fmt.Println("your vault will be permanently unrecoverable.")
+	fmt.Println()
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	return nil
+}
+
+// Add encrypts and adds a file to the vault
+func Add(args []string) error {
+	p, err := parseArgs(args, append([]string{"vault", "name"}, filterValueFlags...), append([]string{"r", "recursive", "dry-run"}, filterBoolFlags...))
+	if err != nil {
+		return err
+	}
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix add [-r] <file-or-directory> [vault-path]\n       vaultix add --name <stored-name> - [vault-path]")
+	}
+	recursive := p.bool("r") || p.bool("recursive")
+	if !recursive && (hasFilterFlags(p) || p.bool("dry-run")) {
+		return fmt.Errorf("--include, --exclude, --hidden and --dry-run require -r")
+	}
+
+	if p.positional[0] == "-" {
+		if recursive {
+			return fmt.Errorf("-r cannot be used when reading from stdin")
+		}
+		return addStdin(p)
+	}
+	if p.value("name") != "" {
+		return fmt.Errorf("--name is only used when reading from stdin (-)")
+	}
+
+	filePath := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return fmt.Errorf("invalid file path: %w", err)
+	}
+
+	// Check if file exists
+	info, err := os.Lstat(absFilePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", absFilePath)
+	}
+	if err == nil && info.IsDir() && !recursive {
+		return fmt.Errorf("%s is a directory (use -r to add it recursively)", filePath)
+	}
+
+	if p.bool("dry-run") {
+		v, err := vaultix.Open(absVaultPath)
+		if err != nil {
+			return err
+		}
+		plan, err := v.PlanAddDirectory(absFilePath, fileFilter(p))
+		if err != nil {
+			return fmt.Errorf("failed to plan adding directory: %w", err)
+		}
+		printTreePlan(plan)
+		return nil
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	// Add file
+	if recursive && info.IsDir() {
+		spinner := NewProgressSpinner("Adding")
+		spinner.Start()
+
+		session.SetProgressCallback(func(current, total int, message string) {
+			spinner.Update(current, total, message)
+		})
+
+		count, err := session.AddDirectory(absFilePath, fileFilter(p))
+
+		spinner.Stop()
+		<-spinner.done
+
+		if err != nil {
+			return fmt.Errorf("failed to add directory (%d file(s) added): %w", count, err)
+		}
+		fmt.Printf("✓ Added %d file(s) from %s\n", count, filePath)
+		return nil
+	}
+
+	spinner := NewProgressSpinner("Adding")
+	spinner.Start()
+
+	fileName := filepath.Base(absFilePath)
+	spinner.Update(1, 1, fileName)
+
+	version, err := session.AddFile(absFilePath)
+
+	spinner.Stop()
+	<-spinner.done
+
+	if err != nil {
+		return fmt.Errorf("failed to add file: %w", err)
+	}
+
+	if version > 1 {
+		fmt.Printf("✓ File updated: %s (version %d)\n", fileName, version)
+		return nil
+	}
+	fmt.Printf("✓ File added: %s\n", fileName)
+	return nil
+}
+
+// List displays all files in the vault
+func List(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, []string{"tree"})
+	if err != nil {
+		return err
+	}
+
+	vaultPath := ""
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	// List files
+	files, err := session.ListFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	if len(files) == 0 {
+		fmt.Println("Vault is empty")
+		return nil
+	}
+
+	fmt.Printf("Files in vault (%d):\n", len(files))
+	if p.bool("tree") {
+		printTree(files)
+		return nil
+	}
+	for _, f := range files {
+		if f.IsSymlink() {
+			fmt.Printf("  %s -> %s (symbolic link)\n", f.Name, f.LinkTarget)
+			continue
+		}
+		fmt.Printf("  %s (%d bytes, modified: %s)\n",
+			f.Name,
+			f.Size,
+			f.ModTime.Format("2006-01-02 15:04:05"))
+	}
+
+	return nil
+}
+
+// Log lists the stored versions of a file
+func Log(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
+	if err != nil {
+		return err
+	}
+
+	// Accept both "log <file> [vault]" and "log <vault> <file>"
+	vaultPath, fileName, rest := splitVaultFileArgs(p)
+	if vaultPath == "" {
+		vaultPath = rest
+	}
+	if fileName == "" {
+		return fmt.Errorf("usage: vaultix log <file> [vault-path] (or --vault <path>)")
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	actualFileName, versions, err := session.FileHistory(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read file history: %w", err)
+	}
+
+	fmt.Printf("Versions of %s (%d):\n", actualFileName, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		fv := versions[i]
+		current := ""
+		if i == len(versions)-1 {
+			current = " (current)"
+		}
+		fmt.Printf("  %3d  %s  %d bytes, modified: %s%s\n",
+			fv.Version,
+			fv.AddedAt.Format("2006-01-02 15:04:05"),
+			fv.Size,
+			fv.ModTime.Format("2006-01-02 15:04:05"),
+			current)
+	}
+
+	return nil
+}
+
+// Verify reports whether a local file is identical to its copy in the vault
+func Verify(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "name"}, nil)
+	if err != nil {
+		return err
+	}
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix verify <local-file> [vault-path] [--name <stored-name>]")
+	}
+
+	localPath := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(localPath); err != nil {
+		return fmt.Errorf("file not found: %s", localPath)
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	result, err := session.VerifyFile(localPath, p.value("name"))
+	if err != nil {
+		return fmt.Errorf("failed to verify file: %w", err)
+	}
+
+	if !result.Match {
+		return fmt.Errorf("%s differs from %s in the vault: %s", localPath, result.Name, result.Reason)
+	}
+
+	fmt.Printf("✓ %s is identical to %s in the vault\n", localPath, result.Name)
+	return nil
+}
+
+// Update stores a modified local file as the new contents of its vaulted copy
+func Update(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "name"}, []string{"all"})
+	if err != nil {
+		return err
+	}
+
+	if p.bool("all") {
+		return updateAll(p)
+	}
+
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix update <local-file> [vault-path] [--name <stored-name>]\n       vaultix update --all [dir] [--vault <path>]")
+	}
+
+	localPath := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(localPath); err != nil {
+		return fmt.Errorf("file not found: %s", localPath)
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	result, err := session.UpdateFile(localPath, p.value("name"))
+	if err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+
+	if !result.Changed {
+		fmt.Printf("✓ %s already matches the vault (version %d)\n", result.Name, result.Version)
+		return nil
+	}
+	fmt.Printf("✓ Updated %s from %s (version %d)\n", result.Name, localPath, result.Version)
+	return nil
+}
+
+// updateAll pushes every modified file in a directory back into the vault
+func updateAll(p *parsedArgs) error {
+	if p.value("name") != "" {
+		return fmt.Errorf("--name cannot be used with --all")
+	}
+
+	dir := "."
+	if len(p.positional) >= 1 {
+		dir = p.positional[0]
+	}
+
+	absVaultPath, err := resolveVault(p, "")
+	if err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory not found: %s", dir)
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	spinner := NewProgressSpinner("Updating")
+	spinner.Start()
+
+	session.SetProgressCallback(func(current, total int, message string) {
+		spinner.Update(current, total, message)
+	})
+
+	results, err := session.UpdateAll(absDir)
+
+	spinner.Stop()
+	<-spinner.done
+
+	updated := 0
+	for _, result := range results {
+		if result.NotFile {
+			fmt.Printf("! Skipped %s: %s is not a file\n", result.Name, result.Path)
+			continue
+		}
+		fmt.Printf("✓ Updated %s (version %d)\n", result.Name, result.Version)
+		updated++
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update files (%d file(s) updated): %w", updated, err)
+	}
+	if updated == 0 {
+		fmt.Println("✓ No modified files; the vault is up to date")
+		return nil
+	}
+	fmt.Printf("✓ Updated %d file(s)\n", updated)
+	return nil
+}
+
+// Status compares a directory with the vault, like git status
+func Status(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, []string{"short"})
+	if err != nil {
+		return err
+	}
+
+	dir := "."
+	if len(p.positional) >= 1 {
+		dir = p.positional[0]
+	}
+
+	absVaultPath, err := resolveVault(p, "")
+	if err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid directory: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory not found: %s", dir)
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	statuses, err := session.Status(absDir)
+	if err != nil {
+		return fmt.Errorf("failed to compare with vault: %w", err)
+	}
+
+	if p.bool("short") {
+		codes := map[vaultix.FileState]string{
+			vaultix.StateModified:  "M",
+			vaultix.StateNew:       "?",
+			vaultix.StateVaultOnly: "D",
+			vaultix.StateNotFile:   "!",
+		}
+		for _, st := range statuses {
+			if code, ok := codes[st.State]; ok {
+				fmt.Printf("%s %s\n", code, st.Name)
+			}
+		}
+		return nil
+	}
+
+	groups := []struct {
+		state vaultix.FileState
+		title string
+	}{
+		{vaultix.StateModified, "Modified (differs from the vault)"},
+		{vaultix.StateNew, "New (not in the vault)"},
+		{vaultix.StateVaultOnly, "Vault only (not on disk)"},
+		{vaultix.StateNotFile, "Not a file (skipped by update)"},
+	}
+
+	unchanged := 0
+	for _, st := range statuses {
+		if st.State == vaultix.StateUnchanged {
+			unchanged++
+		}
+	}
+
+	fmt.Printf("Comparing %s with vault %s\n", absDir, absVaultPath)
+	for _, group := range groups {
+		var names []string
+		for _, st := range statuses {
+			if st.State == group.state {
+				names = append(names, st.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		fmt.Println()
+		fmt.Printf("%s:\n", group.title)
+		for _, name := range names {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	fmt.Println()
+	if unchanged == len(statuses) {
+		fmt.Printf("✓ All %d file(s) match the vault\n", unchanged)
+		return nil
+	}
+	fmt.Printf("%d file(s) unchanged\n", unchanged)
+	return nil
+}
+
+// Prune removes earlier file versions not kept by the retention rules
+func Prune(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "keep-last", "keep-within"}, nil)
+	if err != nil {
+		return err
+	}
+
+	var opts vaultix.PruneOptions
+	if value := p.value("keep-last"); value != "" {
+		opts.KeepLast, err = strconv.Atoi(value)
+		if err != nil || opts.KeepLast < 1 {
+			return fmt.Errorf("invalid --keep-last: %s", value)
+		}
+	}
+	if value := p.value("keep-within"); value != "" {
+		opts.KeepWithin, err = vaultix.ParseRetention(value)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.KeepLast == 0 && opts.KeepWithin == 0 {
+		return fmt.Errorf("usage: vaultix prune [vault-path] --keep-last <n> and/or --keep-within <duration>")
+	}
+
+	vaultPath := ""
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	result, err := session.Prune(opts)
+	if err != nil {
+		return fmt.Errorf("failed to prune versions: %w", err)
+	}
+
+	fmt.Printf("✓ Pruned %d version(s) from %d file(s), %d chunk(s) deleted\n",
+		result.VersionsRemoved, result.FilesPruned, result.ChunksDeleted)
+	return nil
+}
+
+// Stats displays size and deduplication statistics for the vault
+func Stats(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
+	if err != nil {
+		return err
+	}
+
+	vaultPath := ""
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stats, err := session.Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read vault stats: %w", err)
+	}
+
+	fmt.Printf("Files:              %d\n", stats.Files)
+	fmt.Printf("Unique chunks:      %d\n", stats.Chunks)
+	fmt.Printf("Logical size:       %d bytes\n", stats.LogicalSize)
+	fmt.Printf("Stored size:        %d bytes\n", stats.StoredSize)
+	fmt.Printf("Deduplication:      %.2fx\n", stats.DedupRatio())
+	fmt.Printf("History size:       %d bytes (earlier versions, snapshots, trash)\n", stats.HistorySize)
 
-	// Read password
-	password, err := readPassword("Enter password: ")
+	return nil
+}
+
+// Repack compacts pack files and packs small loose objects
+func Repack(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
 	if err != nil {
 		return err
 	}
 
-	if password == "" {
-		return fmt.Errorf("password cannot be empty")
+	vaultPath := ""
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
 	}
 
-	// Confirm password
-	confirmPassword, err := readPassword("Confirm password: ")
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
 		return err
 	}
 
-	if password != confirmPassword {
-		return fmt.Errorf("passwords do not match")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
 	}
+	defer session.Close()
 
-	// Initialize vault
-	v := vault.New(absPath)
-
-	spinner := NewProgressSpinner("Encrypting")
+	spinner := NewProgressSpinner("Repacking")
 	spinner.Start()
 
-	v.SetProgressCallback(func(current, total int, message string) {
-		spinner.Update(current, total, message)
-	})
-
-	recoveryKey, err := v.Initialize(password)
+	result, err := session.Repack()
 
 	spinner.Stop()
 	<-spinner.done
 
 	if err != nil {
-		return fmt.Errorf("failed to initialize vault: %w", err)
+		return fmt.Errorf("failed to repack vault: %w", err)
 	}
 
-	fmt.Printf("✓ Vault initialized at: %s\n", absPath)
-	fmt.Println("✓ All files have been encrypted")
-	fmt.Println("✓ Original plaintext files have been securely deleted")
-	fmt.Println()
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("             IMPORTANT: RECOVERY KEY")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println()
-	fmt.Println("Your recovery key (save this in a secure location):")
-	fmt.Println()
-	fmt.Printf("  %s\n", crypto.FormatRecoveryKeyForDisplay(recoveryKey))
-	fmt.Println()
-	fmt.Println("This recovery key can unlock your vault if you forget your password.")
-	fmt.Println("Store it safely - if you lose both your password AND recovery key,")
-	fmt.Println("your vault will be permanently unrecoverable.")
-	fmt.Println()
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("✓ Repacked vault (%d pack(s) compacted, %d object(s) packed, %d bytes reclaimed)\n",
+		result.PacksRewritten, result.ObjectsPacked, result.BytesReclaimed)
 	return nil
 }
 
-// Add encrypts and adds a file to the vault
-func Add(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: vaultix add <file> [vault-path]")
+// Convert copies a vault into the other storage layout (directory <-> container file)
+func Convert(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
+	if err != nil {
+		return err
 	}
 
-	filePath := args[0]
-	vaultPath := "."
-	if len(args) >= 2 {
-		vaultPath = args[1]
+	// vaultix convert [source] <destination>
+	sourcePath, destPath := "", ""
+	switch {
+	case len(p.positional) == 1:
+		destPath = p.positional[0]
+	case len(p.positional) == 2 && p.value("vault") == "":
+		sourcePath, destPath = p.positional[0], p.positional[1]
+	default:
+		return fmt.Errorf("usage: vaultix convert [source-vault] <destination>")
 	}
 
-	// Convert to absolute paths
-	absVaultPath, err := filepath.Abs(vaultPath)
+	absVaultPath, err := resolveVault(p, sourcePath)
 	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+		return err
 	}
 
-	absFilePath, err := filepath.Abs(filePath)
+	absDestPath, err := filepath.Abs(destPath)
 	if err != nil {
-		return fmt.Errorf("invalid file path: %w", err)
+		return fmt.Errorf("invalid destination path: %w", err)
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	if vaultix.Exists(absDestPath) {
+		return fmt.Errorf("vault already exists at: %s", absDestPath)
 	}
-
-	// Check if file exists
-	if _, err := os.Stat(absFilePath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", absFilePath)
+	if vaultix.IsContainer(absVaultPath) == vaultix.IsContainer(absDestPath) {
+		return fmt.Errorf("source and destination use the same layout (use a %s file for a container)", vaultix.ContainerExt)
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
 		return err
 	}
+	defer session.Close()
 
-	// Add file
-	v := vault.New(absVaultPath)
-
-	spinner := NewProgressSpinner("Adding")
+	spinner := NewProgressSpinner("Converting")
 	spinner.Start()
 
-	fileName := filepath.Base(absFilePath)
-	spinner.Update(1, 1, fileName)
+	session.SetProgressCallback(func(current, total int, message string) {
+		spinner.Update(current, total, message)
+	})
 
-	err = v.AddFile(password, absFilePath)
+	err = session.Convert(absDestPath)
 
 	spinner.Stop()
 	<-spinner.done
 
 	if err != nil {
-		return fmt.Errorf("failed to add file: %w", err)
+		return fmt.Errorf("failed to convert vault: %w", err)
 	}
 
-	fmt.Printf("✓ File added: %s\n", fileName)
+	fmt.Printf("✓ Vault converted: %s -> %s\n", absVaultPath, absDestPath)
+	fmt.Println("  The original vault was left in place; remove it once you have checked the copy")
 	return nil
 }
 
-// List displays all files in the vault
-func List(args []string) error {
-	vaultPath := "."
-	if len(args) >= 1 {
-		vaultPath = args[0]
+// Snapshot manages point-in-time snapshots of the vault
+func Snapshot(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "message"}, nil)
+	if err != nil {
+		return err
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
-	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+	// vaultix snapshot <create|list> [vault]
+	// vaultix snapshot <restore|delete> <id> [vault]
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix snapshot <create|list|restore|delete> [id] [vault]")
+	}
+	action := p.positional[0]
+	rest := p.positional[1:]
+
+	snapshotID := ""
+	switch action {
+	case "create", "list":
+	case "restore", "delete":
+		if len(rest) < 1 {
+			return fmt.Errorf("usage: vaultix snapshot %s <id> [vault]", action)
+		}
+		snapshotID, rest = rest[0], rest[1:]
+	default:
+		return fmt.Errorf("unknown snapshot command: %s", action)
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	vaultPath := ""
+	if len(rest) >= 1 {
+		vaultPath = rest[0]
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
 		return err
 	}
 
-	// List files
-	v := vault.New(absVaultPath)
-	files, err := v.ListFiles(password)
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		return err
 	}
+	defer session.Close()
 
-	if len(files) == 0 {
-		fmt.Println("Vault is empty")
-		return nil
-	}
+	switch action {
+	case "create":
+		snap, err := session.CreateSnapshot(p.value("message"))
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot: %w", err)
+		}
+		fmt.Printf("✓ Snapshot created: %s (%d file(s))\n", snap.ID, snap.FileCount)
 
-	fmt.Printf("Files in vault (%d):\n", len(files))
-	for _, f := range files {
-		fmt.Printf("  %s (%d bytes, modified: %s)\n",
-			f.OriginalName,
-			f.Size,
-			f.ModTime.Format("2006-01-02 15:04:05"))
+	case "list":
+		snapshots, err := session.ListSnapshots()
+		if err != nil {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots")
+			return nil
+		}
+
+		fmt.Printf("Snapshots (%d):\n\n", len(snapshots))
+		for _, snap := range snapshots {
+			fmt.Printf("  %s  %s  %d file(s), %d bytes",
+				snap.ID, snap.CreatedAt.Format("2006-01-02 15:04:05"), snap.FileCount, snap.TotalSize)
+			if snap.Description != "" {
+				fmt.Printf("  %s", snap.Description)
+			}
+			fmt.Println()
+		}
+
+	case "restore":
+		backup, err := session.RestoreSnapshot(snapshotID)
+		if err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+		fmt.Printf("✓ Snapshot restored: %s\n", snapshotID)
+		fmt.Printf("  Previous files saved as snapshot %s\n", backup.ID)
+
+	case "delete":
+		snap, err := session.DeleteSnapshot(snapshotID)
+		if err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+		fmt.Printf("✓ Snapshot deleted: %s\n", snap.ID)
 	}
 
 	return nil
@@ -215,77 +884,87 @@ func List(args []string) error {
 
 // Extract decrypts and extracts a file from the vault
 func Extract(args []string) error {
-	vaultPath := "."
-	fileName := ""
-	outputPath := ""
-
-	// Parse arguments flexibly
-	if len(args) >= 1 {
-		// If first arg is a vault path (contains / or is .), use it
-		if args[0] == "." || args[0] == ".." || filepath.IsAbs(args[0]) || filepath.Dir(args[0]) != "." {
-			vaultPath = args[0]
-			if len(args) >= 2 {
-				fileName = args[1]
-			}
-			if len(args) >= 3 {
-				outputPath = args[2]
-			}
-		} else {
-			// First arg is the filename
-			fileName = args[0]
-			if len(args) >= 2 {
-				outputPath = args[1]
-			}
+	p, err := parseArgs(args, []string{"vault", "snapshot", "version", "on-conflict", "tmpdir"}, append([]string{"fifo"}, restoreFlags...))
+	if err != nil {
+		return err
+	}
+
+	vaultPath, fileName, outputPath := splitVaultFileArgs(p)
+	if p.bool("fifo") {
+		if fileName == "" {
+			return fmt.Errorf("--fifo requires a file name")
 		}
+		if outputPath != "" || p.value("on-conflict") != "" {
+			return fmt.Errorf("--fifo cannot be used with an output path or --on-conflict")
+		}
+	} else if p.value("tmpdir") != "" {
+		return fmt.Errorf("--tmpdir requires --fifo")
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
+	version := 0
+	if value := p.value("version"); value != "" {
+		version, err = strconv.Atoi(value)
+		if err != nil || version < 1 {
+			return fmt.Errorf("invalid version: %s", value)
+		}
+		if fileName == "" {
+			return fmt.Errorf("--version requires a file name")
+		}
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+		return err
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	opts := vaultix.ExtractOptions{Snapshot: p.value("snapshot"), Version: version, Restore: restoreOptions(p)}
+	if p.bool("fifo") {
+		return extractFIFO(absVaultPath, fileName, p.value("tmpdir"), &opts)
+	}
+	prompter, err := setConflictPolicy(p, &opts)
+	if err != nil {
+		return err
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
 		return err
 	}
+	defer session.Close()
 
 	// Extract file(s)
-	v := vault.New(absVaultPath)
-
 	// If no filename specified, extract all files
 	if fileName == "" {
 		spinner := NewProgressSpinner("Extracting")
+		prompter.spinner = spinner
 		spinner.Start()
 
-		v.SetProgressCallback(func(current, total int, message string) {
+		session.SetProgressCallback(func(current, total int, message string) {
 			spinner.Update(current, total, message)
 		})
 
-		count, err := v.ExtractAllFiles(password, outputPath)
+		result, err := session.ExtractAllFiles(outputPath, &opts)
 
 		spinner.Stop()
 		<-spinner.done
 
 		if err != nil {
-			return fmt.Errorf("failed to extract files: %w", err)
+			printExtractSummary(result)
+			return fmt.Errorf("failed to extract files (%d extracted): %w", len(result.Extracted), err)
 		}
-		fmt.Printf("✓ Extracted %d file(s)\n", count)
+		fmt.Printf("✓ Extracted %d file(s)\n", len(result.Extracted))
+		printExtractSummary(result)
 		return nil
 	}
 
 	// Extract single file with fuzzy matching
 	spinner := NewProgressSpinner("Extracting")
+	prompter.spinner = spinner
 	spinner.Start()
 	spinner.Update(1, 1, fileName)
 
-	actualFileName, err := v.ExtractFile(password, fileName, outputPath)
+	result, err := session.ExtractFile(fileName, outputPath, &opts)
 
 	spinner.Stop()
 	<-spinner.done
@@ -294,81 +973,82 @@ func Extract(args []string) error {
 		return fmt.Errorf("failed to extract file: %w", err)
 	}
 
-	fmt.Printf("✓ File extracted: %s\n", actualFileName)
+	for _, name := range result.Extracted {
+		fmt.Printf("✓ File extracted: %s\n", name)
+	}
+	printExtractSummary(result)
 	return nil
 }
 
 // Drop extracts and removes file(s) from the vault (destructive operation)
 func Drop(args []string) error {
-	vaultPath := "."
-	fileName := ""
-	outputPath := ""
-
-	// Parse arguments flexibly (same as Extract)
-	if len(args) >= 1 {
-		if args[0] == "." || args[0] == ".." || filepath.IsAbs(args[0]) || filepath.Dir(args[0]) != "." {
-			vaultPath = args[0]
-			if len(args) >= 2 {
-				fileName = args[1]
-			}
-			if len(args) >= 3 {
-				outputPath = args[2]
-			}
-		} else {
-			fileName = args[0]
-			if len(args) >= 2 {
-				outputPath = args[1]
-			}
-		}
+	p, err := parseArgs(args, []string{"vault", "on-conflict"}, append([]string{"permanent"}, restoreFlags...))
+	if err != nil {
+		return err
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
+	vaultPath, fileName, outputPath := splitVaultFileArgs(p)
+
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+		return err
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	opts := vaultix.ExtractOptions{Restore: restoreOptions(p)}
+	prompter, err := setConflictPolicy(p, &opts)
+	if err != nil {
+		return err
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
 		return err
 	}
+	defer session.Close()
 
 	// Drop file(s)
-	v := vault.New(absVaultPath)
+	permanent := p.bool("permanent")
 
 	// If no filename specified, drop all files
 	if fileName == "" {
 		spinner := NewProgressSpinner("Dropping")
+		prompter.spinner = spinner
 		spinner.Start()
 
-		v.SetProgressCallback(func(current, total int, message string) {
+		session.SetProgressCallback(func(current, total int, message string) {
 			spinner.Update(current, total, message)
 		})
 
-		count, err := v.DropAllFiles(password, outputPath)
+		result, err := session.DropAllFiles(outputPath, &opts, permanent)
 
 		spinner.Stop()
 		<-spinner.done
 
 		if err != nil {
-			return fmt.Errorf("failed to drop files: %w", err)
+			printExtractSummary(result)
+			return fmt.Errorf("failed to drop files (%d dropped): %w", len(result.Extracted), err)
+		}
+		fmt.Printf("✓ Dropped %d file(s) from vault\n", len(result.Extracted))
+		printExtractSummary(result)
+		if len(result.Skipped) > 0 {
+			fmt.Println("Skipped files were left in the vault.")
 		}
-		fmt.Printf("✓ Dropped %d file(s) from vault\n", count)
 		return nil
 	}
 
 	// Drop single file
+	fileName, err = resolveFileName(session, fileName, "Extract and remove")
+	if err != nil {
+		return fmt.Errorf("failed to drop file: %w", err)
+	}
+
 	spinner := NewProgressSpinner("Dropping")
+	prompter.spinner = spinner
 	spinner.Start()
 	spinner.Update(1, 1, fileName)
 
-	actualFileName, err := v.DropFile(password, fileName, outputPath)
+	result, err := session.DropFile(fileName, outputPath, &opts, permanent)
 
 	spinner.Stop()
 	<-spinner.done
@@ -377,36 +1057,48 @@ func Drop(args []string) error {
 		return fmt.Errorf("failed to drop file: %w", err)
 	}
 
-	fmt.Printf("✓ Dropped: %s (extracted and removed from vault)\n", actualFileName)
+	for _, name := range result.Extracted {
+		fmt.Printf("✓ Dropped: %s (extracted and removed from vault)\n", name)
+	}
+	printExtractSummary(result)
+	if len(result.Skipped) > 0 {
+		fmt.Println("Skipped files were left in the vault.")
+	}
 	return nil
 }
 
 // Clear removes all files from the vault without extracting them
 func Clear(args []string) error {
-	vaultPath := "."
-	if len(args) >= 1 {
-		vaultPath = args[0]
+	p, err := parseArgs(args, []string{"vault"}, []string{"permanent"})
+	if err != nil {
+		return err
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
-	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+	vaultPath := ""
+	if len(p.positional) >= 1 {
+		vaultPath = p.positional[0]
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
 		return err
 	}
+	defer session.Close()
+
+	permanent := p.bool("permanent")
 
 	// Confirm dangerous operation
-	fmt.Print("⚠️  This will DELETE all files from the vault WITHOUT extracting them. Continue? (yes/no): ")
+	if permanent {
+		fmt.Print("⚠️  This will permanently DELETE all files from the vault WITHOUT extracting them. Continue? (yes/no): ")
+	} else {
+		fmt.Print("⚠️  This will move all files in the vault to the trash WITHOUT extracting them. Continue? (yes/no): ")
+	}
 	var confirm string
 	fmt.Scanln(&confirm)
 	if confirm != "yes" {
@@ -414,85 +1106,248 @@ func Clear(args []string) error {
 	}
 
 	// Clear vault
-	v := vault.New(absVaultPath)
-	if err := v.ClearVault(password); err != nil {
+	if err := session.Clear(permanent); err != nil {
 		return fmt.Errorf("failed to clear vault: %w", err)
 	}
 
-	fmt.Println("✓ Vault cleared (all files removed)")
+	if permanent {
+		fmt.Println("✓ Vault cleared (all files removed)")
+		return nil
+	}
+	fmt.Println("✓ Vault cleared (all files moved to trash, see: vaultix trash list)")
 	return nil
 }
 
 // Remove removes a file from the vault
 func Remove(args []string) error {
-	if len(args) < 1 {
+	p, err := parseArgs(args, []string{"vault"}, []string{"permanent"})
+	if err != nil {
+		return err
+	}
+	if len(p.positional) < 1 {
 		return fmt.Errorf("usage: vaultix remove <file> [vault-path]")
 	}
 
-	fileName := args[0]
-	vaultPath := "."
-	if len(args) >= 2 {
-		vaultPath = args[1]
+	fileName := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
-	}
-
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+		return err
 	}
 
-	// Read password
-	password, err := readPassword("Enter vault password: ")
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
 	if err != nil {
 		return err
 	}
+	defer session.Close()
 
 	// Remove file
-	v := vault.New(absVaultPath)
-	if err := v.RemoveFile(password, fileName); err != nil {
+	action := "Move to trash"
+	if p.bool("permanent") {
+		action = "Permanently delete"
+	}
+	actualFileName, err := resolveFileName(session, fileName, action)
+	if err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	if err := session.RemoveFile(actualFileName, p.bool("permanent")); err != nil {
 		return fmt.Errorf("failed to remove file: %w", err)
 	}
 
-	fmt.Printf("File removed: %s\n", fileName)
+	if p.bool("permanent") {
+		fmt.Printf("File removed: %s\n", actualFileName)
+		return nil
+	}
+	fmt.Printf("File moved to trash: %s (undo with: vaultix trash restore %s)\n", actualFileName, actualFileName)
 	return nil
 }
 
-// Recover extracts files using the recovery key instead of password
-func Recover(args []string) error {
-	vaultPath := "."
-	fileName := ""
-	outputPath := ""
+// Trash lists, restores or permanently deletes removed files
+func Trash(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
+	if err != nil {
+		return err
+	}
 
-	// Parse arguments: vaultix recover [vault] [file] [output]
-	if len(args) >= 1 && (args[0] == "." || filepath.IsAbs(args[0]) || filepath.Dir(args[0]) != ".") {
-		vaultPath = args[0]
-		if len(args) >= 2 {
-			fileName = args[1]
+	// vaultix trash <list|empty> [vault]
+	// vaultix trash restore <file-or-id> [vault]
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix trash <list|restore|empty> [file] [vault]")
+	}
+	action := p.positional[0]
+	rest := p.positional[1:]
+
+	query := ""
+	switch action {
+	case "list", "empty":
+	case "restore":
+		if len(rest) < 1 {
+			return fmt.Errorf("usage: vaultix trash restore <file-or-id> [vault]")
+		}
+		query, rest = rest[0], rest[1:]
+	default:
+		return fmt.Errorf("unknown trash command: %s", action)
+	}
+
+	vaultPath := ""
+	if len(rest) >= 1 {
+		vaultPath = rest[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	switch action {
+	case "list":
+		entries, err := session.ListTrash()
+		if err != nil {
+			return fmt.Errorf("failed to list trash: %w", err)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("Trash is empty")
+			return nil
+		}
+
+		fmt.Printf("Files in trash (%d):\n", len(entries))
+		for _, entry := range entries {
+			fmt.Printf("  %s  %s (%d bytes, removed: %s)\n",
+				entry.ID,
+				entry.File.Name,
+				entry.File.Size,
+				entry.DeletedAt.Format("2006-01-02 15:04:05"))
+		}
+
+	case "restore":
+		id, err := resolveTrashEntry(session, query)
+		if err != nil {
+			return fmt.Errorf("failed to restore file: %w", err)
+		}
+		entry, err := session.RestoreFromTrash(id)
+		if err != nil {
+			return fmt.Errorf("failed to restore file: %w", err)
 		}
-		if len(args) >= 3 {
-			outputPath = args[2]
+		fmt.Printf("✓ Restored from trash: %s\n", entry.File.Name)
+
+	case "empty":
+		// Confirm dangerous operation
+		fmt.Print("⚠️  This will permanently DELETE all files in the trash. Continue? (yes/no): ")
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "yes" {
+			return fmt.Errorf("operation cancelled")
 		}
-	} else if len(args) >= 1 {
-		fileName = args[0]
-		if len(args) >= 2 {
-			outputPath = args[1]
+
+		count, err := session.EmptyTrash()
+		if err != nil {
+			return fmt.Errorf("failed to empty trash: %w", err)
 		}
+		fmt.Printf("✓ Trash emptied (%d file(s) deleted)\n", count)
 	}
 
-	// Convert to absolute path
-	absVaultPath, err := filepath.Abs(vaultPath)
+	return nil
+}
+
+// Config shows or changes the vault settings
+func Config(args []string) error {
+	p, err := parseArgs(args, []string{"vault"}, nil)
+	if err != nil {
+		return err
+	}
+
+	// vaultix config [vault]
+	// vaultix config set <key> <value> [vault]
+	key, value := "", ""
+	rest := p.positional
+	if len(rest) >= 1 && rest[0] == "set" {
+		if len(rest) < 3 {
+			return fmt.Errorf("usage: vaultix config set <key> <value> [vault]")
+		}
+		key, value, rest = rest[1], rest[2], rest[3:]
+	}
+
+	vaultPath := ""
+	if len(rest) >= 1 {
+		vaultPath = rest[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Settings are kept in the encrypted metadata
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	config, err := session.Config()
+	if err != nil {
+		return err
+	}
+
+	if key == "" {
+		fmt.Printf("trash-retention  %s\n", config.TrashRetention)
+		fmt.Printf("wipe             %s\n", config.Wipe)
+		if reason := session.Vault().WipeWarning(); reason != "" && config.Wipe != "none" {
+			fmt.Printf("\nNote: overwriting may leave plaintext recoverable here: %s\n", reason)
+		}
+		return nil
+	}
+
+	switch key {
+	case "trash-retention":
+		config.TrashRetention = value
+	case "wipe":
+		config.Wipe = value
+	default:
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+
+	// SetConfig rejects invalid values
+	if err := session.SetConfig(config); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ %s set to %s\n", key, value)
+	return nil
+}
+
+// Recover extracts files using the recovery key instead of password
+func Recover(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "snapshot", "on-conflict"}, restoreFlags)
+	if err != nil {
+		return err
+	}
+
+	// Parse arguments: vaultix recover [vault] [file] [output]
+	vaultPath, fileName, outputPath := splitVaultFileArgs(p)
+
+	absVaultPath, err := resolveVault(p, vaultPath)
 	if err != nil {
-		return fmt.Errorf("invalid vault path: %w", err)
+		return err
 	}
 
-	// Check if vault exists
-	if !storage.VaultExists(absVaultPath) {
-		return fmt.Errorf("vault not found at: %s", absVaultPath)
+	opts := vaultix.ExtractOptions{Snapshot: p.value("snapshot"), Restore: restoreOptions(p)}
+	prompter, err := setConflictPolicy(p, &opts)
+	if err != nil {
+		return err
 	}
 
 	// Read recovery key
@@ -502,73 +1357,74 @@ func Recover(args []string) error {
 		return fmt.Errorf("failed to read recovery key: %w", err)
 	}
 
-	// Remove dashes from the recovery key
-	recoveryKeyStr = filepath.ToSlash(recoveryKeyStr)
-	recoveryKeyStr = filepath.Clean(recoveryKeyStr)
-	// Simple approach: remove all non-hex characters
-	cleanKey := ""
-	for _, c := range recoveryKeyStr {
-		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
-			cleanKey += string(c)
-		}
-	}
-
-	recoveryKey, err := crypto.DecodeRecoveryKeyHex(cleanKey)
+	recoveryKey, err := vaultix.ParseRecoveryKey(recoveryKeyStr)
 	if err != nil {
 		return fmt.Errorf("invalid recovery key: %w", err)
 	}
 
 	// Unlock vault with recovery key
-	v := vault.New(absVaultPath)
-	masterKey, err := v.UnlockWithRecoveryKey(recoveryKey)
+	v, err := vaultix.Open(absVaultPath)
+	if err != nil {
+		return err
+	}
+	session, err := v.Unlock(recoveryKey)
 	if err != nil {
 		return fmt.Errorf("failed to unlock vault with recovery key: %w", err)
 	}
+	defer session.Close()
 
 	// If no filename specified, extract all
 	if fileName == "" {
-		return recoverAllFiles(v, masterKey, outputPath)
+		return recoverAllFiles(session, outputPath, &opts, prompter)
 	}
 
 	// Extract specific file
-	return recoverFile(v, masterKey, fileName, outputPath)
+	return recoverFile(session, fileName, outputPath, &opts)
 }
 
-// recoverFile extracts a single file using master key
-func recoverFile(v *vault.Vault, masterKey []byte, fileName, destPath string) error {
-	actualFileName, err := v.ExtractFileWithMasterKey(masterKey, fileName, destPath)
+// recoverFile extracts a single file from a vault unlocked by the recovery key
+func recoverFile(session *vaultix.Session, fileName, destPath string, opts *vaultix.ExtractOptions) error {
+	result, err := session.ExtractFile(fileName, destPath, opts)
 	if err != nil {
 		return fmt.Errorf("failed to extract file: %w", err)
 	}
 
-	outputLocation := destPath
-	if outputLocation == "" || outputLocation == "." {
-		outputLocation = actualFileName
-	}
+	for _, name := range result.Extracted {
+		outputLocation := destPath
+		if outputLocation == "" || outputLocation == "." {
+			outputLocation = name
+		}
+		for _, renamed := range result.Renamed {
+			outputLocation = renamed.Path
+		}
 
-	fmt.Printf("✓ Recovered: %s -> %s\n", actualFileName, outputLocation)
+		fmt.Printf("✓ Recovered: %s -> %s\n", name, outputLocation)
+	}
+	printExtractSummary(result)
 	return nil
 }
 
-// recoverAllFiles extracts all files using master key
-func recoverAllFiles(v *vault.Vault, masterKey []byte, destDir string) error {
+// recoverAllFiles extracts all files from a vault unlocked by the recovery key
+func recoverAllFiles(session *vaultix.Session, destDir string, opts *vaultix.ExtractOptions, prompter *conflictPrompter) error {
 	spinner := NewProgressSpinner("Recovering")
+	prompter.spinner = spinner
 	spinner.Start()
 
-	v.SetProgressCallback(func(current, total int, message string) {
+	session.SetProgressCallback(func(current, total int, message string) {
 		spinner.Update(current, total, message)
 	})
 
-	count, err := v.ExtractAllFilesWithMasterKey(masterKey, destDir)
+	result, err := session.ExtractAllFiles(destDir, opts)
 
 	spinner.Stop()
 	<-spinner.done
 
 	if err != nil {
-		return fmt.Errorf("failed to extract files: %w", err)
+		printExtractSummary(result)
+		return fmt.Errorf("failed to extract files (%d recovered): %w", len(result.Extracted), err)
 	}
 
-	if count == 0 {
+	if len(result.Extracted) == 0 && len(result.Skipped) == 0 {
 		fmt.Println("Vault is empty")
 		return nil
 	}
@@ -578,7 +1434,8 @@ func recoverAllFiles(v *vault.Vault, masterKey []byte, destDir string) error {
 		location = destDir
 	}
 
-	fmt.Printf("✓ Recovered %d file(s) to %s\n", count, location)
+	fmt.Printf("✓ Recovered %d file(s) to %s\n", len(result.Extracted), location)
+	printExtractSummary(result)
 	return nil
 }
 
@@ -589,23 +1446,72 @@ func PrintUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  vaultix init [path]              Initialize vault (defaults to current directory)")
 	fmt.Println("  vaultix add <file> [vault]       Add a file to the vault (defaults to current)")
-	fmt.Println("  vaultix list [vault]             List files in the vault (defaults to current)")
-	fmt.Println("  vaultix extract [file] [vault]   Extract file(s) - keeps in vault")
-	fmt.Println("  vaultix drop [file] [vault]      Extract file(s) and remove from vault")
-	fmt.Println("  vaultix remove <file> [vault]    Remove a file from vault (no extraction)")
-	fmt.Println("  vaultix clear [vault]            Remove ALL files from vault (no extraction)")
+	fmt.Println("  vaultix add -r <dir> [vault]     Add a directory tree, keeping relative paths")
+	fmt.Println("  vaultix add --name <name> -      Add stdin to the vault as <name>")
+	fmt.Println("  vaultix list [vault] [--tree]    List files in the vault (defaults to current)")
+	fmt.Println("  vaultix log <file> [vault]       List the stored versions of a file")
+	fmt.Println("  vaultix verify <file> [vault]    Check a local file against its vaulted copy")
+	fmt.Println("  vaultix status [dir]             Show files changed on disk since extraction (--short)")
+	fmt.Println("  vaultix update <file> [vault]    Store a modified file as a new version (--all)")
+	fmt.Println("  vaultix cat <file> [vault]       Decrypt a file to stdout")
+	fmt.Println("  vaultix exec -- <cmd> [args]     Run a command with vaulted secrets (--env, --file-fd)")
+	fmt.Println("  vaultix env export <file>...     Print variables from vaulted .env files (--format)")
+	fmt.Println("  vaultix render <template>        Fill a text/template with secrets (-o, --check)")
+	fmt.Println("  vaultix secret <cmd> [name]      Manage logins, notes and tokens (set, get, list, rm)")
+	fmt.Println("  vaultix edit <file> [vault]      Edit a file in $EDITOR without leaving plaintext on disk")
+	fmt.Println("  vaultix prune [vault]            Remove old versions (--keep-last N, --keep-within 30d)")
+	fmt.Println("  vaultix stats [vault]            Show vault size and deduplication ratio")
+	fmt.Println("  vaultix repack [vault]           Compact pack files after removals")
+	fmt.Println("  vaultix extract [vault] [file]   Extract file(s) - keeps in vault")
+	fmt.Println("  vaultix drop [vault] [file]      Extract file(s) and move them to the trash")
+	fmt.Println("  vaultix remove <file> [vault]    Move a file to the trash (no extraction)")
+	fmt.Println("  vaultix clear [vault]            Move ALL files to the trash (no extraction)")
+	fmt.Println("  vaultix trash <cmd> [vault]      Manage removed files (list, restore <file>, empty)")
+	fmt.Println("  vaultix config [vault]           Show settings (config set <key> <value> to change)")
 	fmt.Println("  vaultix recover [vault] [file]   Unlock vault using recovery key")
+	fmt.Println("  vaultix convert [vault] <dest>   Copy vault between directory and .vtx file layouts")
+	fmt.Println("  vaultix snapshot <cmd> [vault]   Manage snapshots (create, list, restore <id>, delete <id>)")
+	fmt.Println("  vaultix agent [start]            Keep unlocked vaults in memory (--timeout, status, stop)")
+	fmt.Println("  vaultix lock [vault]             Make the agent forget a vault's key (--all for every vault)")
+	fmt.Println()
+	fmt.Println("Every command accepts --vault <path>, where path is a vault directory or a")
+	fmt.Println("single-file container such as secrets.vtx. drop, remove and clear accept")
+	fmt.Println("--permanent to delete files instead of moving them to the trash.")
+	fmt.Println("extract, drop, log and recover take their first argument as the vault only if")
+	fmt.Println("it is . or .., ends in .vtx, or is an existing vault; otherwise use --vault.")
+	fmt.Println("extract, drop and recover restore permissions, ownership (as root) and extended")
+	fmt.Println("attributes; --no-owner and --no-xattrs skip ownership and extended attributes.")
+	fmt.Println("When an output file exists, extract, drop and recover ask what to do (on a")
+	fmt.Println("terminal; otherwise they overwrite). --on-conflict=skip|overwrite|rename|newer")
+	fmt.Println("decides for every file. extract --fifo <file> instead serves the file once")
+	fmt.Println("through a named pipe in memory and prints its path.")
+	fmt.Println("init and add -r skip dotfiles (--hidden includes them) and paths matched by")
+	fmt.Println(".vaultixignore or --exclude; --include selects only matching files, and")
+	fmt.Println("--dry-run shows what would be encrypted and deleted without changing anything.")
+	fmt.Println("While the agent runs, a vault's password is asked once and its key is kept")
+	fmt.Println("until unused for the idle timeout; set VAULTIX_NO_AGENT=1 to bypass it.")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  cd my_secrets && vaultix init    # Encrypt all files in current directory")
-	fmt.Println("  vaultix add newfile.txt          # Add file to current vault")
+	fmt.Println("  vaultix init --dry-run           # Show what init would encrypt and delete")
+	fmt.Println("  vaultix add newfile.txt          # Add file to current vault (again for a new version)")
+	fmt.Println("  vaultix add -r certs --exclude '*.key'  # Add a directory tree, skipping keys")
 	fmt.Println("  vaultix list                     # List files in current vault")
 	fmt.Println("  vaultix extract                  # Extract ALL files (keeps in vault)")
 	fmt.Println("  vaultix extract secret           # Extract one file (keeps in vault)")
+	fmt.Println("  vaultix extract secret --version 2  # Extract an earlier version")
+	fmt.Println("  vaultix prune --keep-last 5 --keep-within 30d")
 	fmt.Println("  vaultix drop secret              # Extract and remove from vault")
 	fmt.Println("  vaultix drop                     # Extract all and clear vault")
-	fmt.Println("  vaultix remove old.txt           # Remove file (no extraction)")
-	fmt.Println("  vaultix clear                    # Remove all (no extraction, asks confirm)")
+	fmt.Println("  vaultix remove old.txt           # Move file to trash (no extraction)")
+	fmt.Println("  vaultix trash restore old.txt    # Undo the removal")
+	fmt.Println("  vaultix config set trash-retention 7d  # Expire trash after a week")
+	fmt.Println("  vaultix clear                    # Move all to trash (asks confirm)")
 	fmt.Println("  vaultix recover                  # Extract all using recovery key")
 	fmt.Println("  vaultix recover . secret.txt     # Extract specific file using recovery key")
+	fmt.Println("  vaultix init secrets.vtx         # Encrypt files into a single-file vault")
+	fmt.Println("  vaultix list --vault secrets.vtx # Use a single-file vault")
+	fmt.Println("  vaultix convert . secrets.vtx    # Copy directory vault into a container file")
+	fmt.Println("  vaultix snapshot create --message \"before cleanup\"")
+	fmt.Println("  vaultix extract --snapshot 1a2b3c4d  # Extract all files as they were in a snapshot")
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+	"golang.org/x/term"
+)
+
+// conflictPrompter asks on the terminal what to do with each output file that
+// already exists
+type conflictPrompter struct {
+	spinner *ProgressSpinner // paused while asking, if set
+	always  vaultix.ConflictPolicy
+}
+
+// setConflictPolicy applies --on-conflict to the extract options
+// Without the flag, conflicts are asked about when stdin is a terminal and
+// overwritten otherwise. The returned prompter answers ConflictAsk; set its
+// spinner once the command has one.
+func setConflictPolicy(p *parsedArgs, opts *vaultix.ExtractOptions) (*conflictPrompter, error) {
+	prompter := &conflictPrompter{}
+	opts.Ask = prompter.ask
+
+	if value := p.value("on-conflict"); value != "" {
+		policy, err := vaultix.ParseConflictPolicy(value)
+		if err != nil {
+			return nil, err
+		}
+		opts.OnConflict = policy
+		return prompter, nil
+	}
+
+	opts.OnConflict = vaultix.ConflictOverwrite
+	if term.IsTerminal(int(syscall.Stdin)) {
+		opts.OnConflict = vaultix.ConflictAsk
+	}
+	return prompter, nil
+}
+
+// ask prompts for one conflict
+// An upper-case answer applies to all remaining conflicts.
+func (c *conflictPrompter) ask(outputPath string, stored *vaultix.FileInfo, existing os.FileInfo) vaultix.ConflictPolicy {
+	if c.always != "" {
+		return c.always
+	}
+
+	if c.spinner != nil {
+		c.spinner.Pause()
+		defer c.spinner.Resume()
+	}
+
+	fmt.Printf("%s already exists\n", outputPath)
+	fmt.Printf("  local: %d bytes, modified %s\n", existing.Size(), existing.ModTime().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  vault: %d bytes, modified %s\n", stored.Size, stored.ModTime.Format("2006-01-02 15:04:05"))
+
+	answers := map[string]vaultix.ConflictPolicy{
+		"o": vaultix.ConflictOverwrite,
+		"s": vaultix.ConflictSkip,
+		"r": vaultix.ConflictRename,
+		"n": vaultix.ConflictNewer,
+	}
+	for {
+		fmt.Print("[o]verwrite, [s]kip, [r]ename, keep [n]ewer (capital letter for all): ")
+		var answer string
+		if _, err := fmt.Scanln(&answer); errors.Is(err, io.EOF) {
+			// No answer can be read; leave the existing file alone
+			fmt.Println()
+			return vaultix.ConflictSkip
+		}
+
+		policy, ok := answers[strings.ToLower(answer)]
+		if !ok || len(answer) != 1 {
+			continue
+		}
+		if answer != strings.ToLower(answer) {
+			c.always = policy
+		}
+		return policy
+	}
+}
+
+// printExtractSummary lists the files skipped or renamed because of conflicts
+func printExtractSummary(result *vaultix.ExtractResult) {
+	if len(result.Skipped) > 0 {
+		fmt.Printf("Skipped %d existing file(s):\n", len(result.Skipped))
+		for _, name := range result.Skipped {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if len(result.Renamed) > 0 {
+		fmt.Printf("Renamed %d file(s) to keep existing files:\n", len(result.Renamed))
+		for _, renamed := range result.Renamed {
+			fmt.Printf("  %s -> %s\n", renamed.Name, renamed.Path)
+		}
+	}
+}
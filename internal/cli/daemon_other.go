@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package cli
+
+import "os/exec"
+
+// detach does nothing on this platform
+func detach(cmd *exec.Cmd) {}
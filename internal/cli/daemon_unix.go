@@ -0,0 +1,13 @@
+//go:build linux || darwin
+
+package cli
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs a command in its own session, so it outlives the terminal
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Edit decrypts a vaulted file to a private temporary directory, opens it in
+// the user's editor and stores the result if it changed
+func Edit(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "tmpdir"}, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix edit <file> [vault-path] [--tmpdir <dir>]")
+	}
+
+	fileName := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	editor := editorCommand()
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPassword)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	// Catch interrupts until the temporary copy is wiped. Ctrl-C reaches the
+	// editor directly from the terminal, so it is left to the editor to act
+	// on; other signals are passed on to it.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	result, err := session.EditFile(fileName, p.value("tmpdir"), func(path string) error {
+		return runEditor(editor, path, signals)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to edit file: %w", err)
+	}
+
+	if !result.Changed {
+		fmt.Printf("✓ No changes to %s\n", result.Name)
+		return nil
+	}
+	fmt.Printf("✓ Updated %s (version %d)\n", result.Name, result.Version)
+	return nil
+}
+
+// editorCommand returns the editor command line from $VISUAL or $EDITOR,
+// falling back to vi
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// runEditor runs the editor on a file and waits for it to exit, passing on
+// termination signals
+func runEditor(editor []string, path string, signals <-chan os.Signal) error {
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start editor %s: %w", editor[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case sig := <-signals:
+			if sig != os.Interrupt {
+				cmd.Process.Signal(sig)
+			}
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("editor %s failed, changes discarded: %w", editor[0], err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envFormats are the output formats of env export
+var envFormats = []string{"shell", "json", "dotenv"}
+
+// Env prints the variables of dotenv files in the vault
+func Env(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "format"}, nil)
+	if err != nil {
+		return err
+	}
+
+	// vaultix env export <file>... [--format shell|json|dotenv]
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix env export <file>... [--vault <path>] [--format shell|json|dotenv]")
+	}
+	if action := p.positional[0]; action != "export" {
+		return fmt.Errorf("unknown env command: %s", action)
+	}
+	files := p.positional[1:]
+	if len(files) == 0 {
+		return fmt.Errorf("usage: vaultix env export <file>... [--vault <path>] [--format shell|json|dotenv]")
+	}
+
+	format := p.value("format")
+	if format == "" {
+		format = "shell"
+	}
+	valid := false
+	for _, f := range envFormats {
+		valid = valid || f == format
+	}
+	if !valid {
+		return fmt.Errorf("invalid format: %s (use %s)", format, strings.Join(envFormats, ", "))
+	}
+
+	absVaultPath, err := resolveVault(p, "")
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault, reading the password from the terminal, as stdout is
+	// usually captured
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	vars, err := session.ReadEnv(files)
+	if err != nil {
+		return fmt.Errorf("failed to read environment: %w", err)
+	}
+
+	var out strings.Builder
+	switch format {
+	case "shell":
+		for _, ev := range vars {
+			fmt.Fprintf(&out, "export %s=%s\n", ev.Key, shellQuote(ev.Value))
+		}
+	case "json":
+		out.WriteString("{")
+		for i, ev := range vars {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			key, _ := json.Marshal(ev.Key)
+			value, _ := json.Marshal(ev.Value)
+			fmt.Fprintf(&out, "\n  %s: %s", key, value)
+		}
+		if len(vars) > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("}\n")
+	case "dotenv":
+		for _, ev := range vars {
+			fmt.Fprintf(&out, "%s=%s\n", ev.Key, dotenvQuote(ev.Value))
+		}
+	}
+
+	_, err = os.Stdout.WriteString(out.String())
+	return err
+}
+
+// shellQuote quotes a value for POSIX shells
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// dotenvQuote double-quotes a value so that vault.ParseDotenv reads it back
+func dotenvQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// ExitError reports that a command run by vaultix did not succeed; vaultix
+// exits with the same status
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// Exec runs a command with secrets from the vault: variables from dotenv
+// files in its environment, and files as in-memory file descriptors
+func Exec(args []string) error {
+	own, command := splitCommand(args)
+	p, err := parseArgs(own, []string{"vault", "env", "file-fd"}, nil)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 || len(p.positional) > 0 {
+		return fmt.Errorf("usage: vaultix exec [--vault <path>] [--env <file>]... [--file-fd <file>]... -- <command> [args...]")
+	}
+
+	envFiles := p.values["env"]
+	fdFiles := p.values["file-fd"]
+	if len(envFiles) == 0 && len(fdFiles) == 0 {
+		return fmt.Errorf("nothing to pass to the command; use --env <file> or --file-fd <file>")
+	}
+
+	absVaultPath, err := resolveVault(p, "")
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault, reading the password from the terminal, leaving stdin to
+	// the command
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	contents, err := session.ReadFiles(append(append([]string(nil), envFiles...), fdFiles...), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read files: %w", err)
+	}
+
+	// Later files override earlier ones, and all override the inherited
+	// environment
+	var lists [][]vaultix.EnvVar
+	for i, data := range contents[:len(envFiles)] {
+		vars, err := vaultix.ParseDotenv(data)
+		clear(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", envFiles[i], err)
+		}
+		lists = append(lists, vars)
+	}
+	env := os.Environ()
+	for _, ev := range vaultix.MergeEnv(lists...) {
+		env = append(env, ev.Key+"="+ev.Value)
+	}
+
+	var memFiles []*os.File
+	defer func() {
+		for _, file := range memFiles {
+			file.Close()
+		}
+	}()
+
+	paths := make([]string, len(fdFiles))
+	for i, data := range contents[len(envFiles):] {
+		file, err := storage.SealedMemFile(path.Base(fdFiles[i]), data)
+		clear(data)
+		if err != nil {
+			return err
+		}
+		memFiles = append(memFiles, file)
+		// ExtraFiles start at descriptor 3 in the child
+		paths[i] = "/proc/self/fd/" + strconv.Itoa(3+i)
+	}
+
+	cmd := exec.Command(command[0], substituteFilePaths(command[1:], paths)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env // on duplicate keys, the last value is used
+	cmd.ExtraFiles = memFiles
+
+	return runCommand(cmd)
+}
+
+// runCommand runs a command to completion, passing on signals sent to
+// vaultix, and returns an *ExitError if it does not succeed
+// Signals the terminal sends to the whole foreground process group, such as
+// Ctrl-C, already reach the command; vaultix ignores them and waits for it.
+func runCommand(cmd *exec.Cmd) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append(forwardedSignals, terminalSignals...)...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Args[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case sig := <-signals:
+			if !isTerminalSignal(sig) {
+				cmd.Process.Signal(sig)
+			}
+		case err := <-done:
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code := exitErr.ExitCode()
+				if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+					// Report death by a signal as shells do
+					code = 128 + int(status.Signal())
+				}
+				return &ExitError{Code: code}
+			}
+			return err
+		}
+	}
+}
+
+// isTerminalSignal reports whether a signal is one of terminalSignals
+func isTerminalSignal(sig os.Signal) bool {
+	for _, s := range terminalSignals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// substituteFilePaths replaces {1}, {2}, ... in a command's arguments with the
+// paths of the files passed with --file-fd, in order; {} stands for {1}
+func substituteFilePaths(args, paths []string) []string {
+	substituted := make([]string, len(args))
+	for i, arg := range args {
+		if len(paths) > 0 {
+			arg = strings.ReplaceAll(arg, "{}", paths[0])
+		}
+		for j, filePath := range paths {
+			arg = strings.ReplaceAll(arg, "{"+strconv.Itoa(j+1)+"}", filePath)
+		}
+		substituted[i] = arg
+	}
+	return substituted
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// extractFIFO decrypts a file into memory and serves it once through a named
+// pipe in a private temporary directory
+// The pipe's path is printed on stdout before waiting for a reader, so that
+// scripts can pass it on; messages go to stderr.
+func extractFIFO(absVaultPath, fileName, tempBase string, opts *vaultix.ExtractOptions) error {
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	contents, err := session.ReadFiles([]string{fileName}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	data := contents[0]
+	defer clear(data)
+
+	dir, err := storage.PrivateTempDir(tempBase)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	fifoPath := filepath.Join(dir, path.Base(fileName))
+	if err := storage.MakeFIFO(fifoPath); err != nil {
+		return err
+	}
+
+	// An interrupt cancels the wait and returns, so that the deferred calls
+	// still clear the data, close the session and remove the pipe
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	fmt.Println(fifoPath)
+	fmt.Fprintln(os.Stderr, "Waiting for a reader...")
+
+	// The writer registers the pipe once open, so that an interrupt can close
+	// it; after an interrupt it writes nothing
+	var (
+		mu          sync.Mutex
+		fifo        *os.File
+		interrupted bool
+	)
+	done := make(chan error, 1)
+	go func() {
+		// Opening blocks until a reader opens the other end
+		f, err := os.OpenFile(fifoPath, os.O_WRONLY, 0)
+		if err != nil {
+			done <- fmt.Errorf("failed to open named pipe: %w", err)
+			return
+		}
+		mu.Lock()
+		if interrupted {
+			mu.Unlock()
+			f.Close()
+			done <- nil
+			return
+		}
+		fifo = f
+		mu.Unlock()
+
+		_, err = f.Write(data)
+		f.Close()
+		if err != nil {
+			err = fmt.Errorf("failed to write to named pipe: %w", err)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-signals:
+		// Closing the pipe ends a write in progress. Opening it for reading
+		// and writing never blocks and lets a writer still waiting to open it
+		// go ahead. Only once the writer has stopped can the data be cleared.
+		mu.Lock()
+		interrupted = true
+		if fifo != nil {
+			fifo.Close()
+		}
+		mu.Unlock()
+		if reader, err := os.OpenFile(fifoPath, os.O_RDWR|syscall.O_NONBLOCK, 0); err == nil {
+			defer reader.Close()
+		}
+		<-done
+		return fmt.Errorf("interrupted; named pipe removed")
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ %s was read; named pipe removed\n", fileName)
+	return nil
+}
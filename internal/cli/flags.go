@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// parsedArgs holds the flags and positional arguments of a command
+type parsedArgs struct {
+	values     map[string][]string
+	bools      map[string]bool
+	positional []string
+}
+
+// parseArgs separates --flags from positional arguments
+// Flags may appear anywhere; valueFlags take a value (--name value or --name=value),
+// boolFlags do not. Single-letter flags may also be given as -x. Everything
+// after "--" is positional.
+func parseArgs(args []string, valueFlags, boolFlags []string) (*parsedArgs, error) {
+	p := &parsedArgs{
+		values: make(map[string][]string),
+		bools:  make(map[string]bool),
+	}
+
+	isValue := make(map[string]bool, len(valueFlags))
+	for _, name := range valueFlags {
+		isValue[name] = true
+	}
+	isBool := make(map[string]bool, len(boolFlags))
+	for _, name := range boolFlags {
+		isBool[name] = true
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			p.positional = append(p.positional, args[i+1:]...)
+			break
+		}
+		if len(arg) == 2 && arg[0] == '-' && arg[1] != '-' {
+			// Single-letter flags may be written with one dash (-r)
+			arg = "-" + arg
+		}
+		if !strings.HasPrefix(arg, "--") {
+			p.positional = append(p.positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch {
+		case isValue[name]:
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			p.values[name] = append(p.values[name], value)
+		case isBool[name]:
+			if hasValue {
+				return nil, fmt.Errorf("flag --%s does not take a value", name)
+			}
+			p.bools[name] = true
+		default:
+			return nil, fmt.Errorf("unknown flag: --%s", name)
+		}
+	}
+
+	return p, nil
+}
+
+// value returns the last value given for a flag, or "" if it was not set
+func (p *parsedArgs) value(name string) string {
+	values := p.values[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// bool reports whether a boolean flag was set
+func (p *parsedArgs) bool(name string) bool {
+	return p.bools[name]
+}
+
+// resolveVault returns the absolute vault path, preferring --vault over the
+// positional path, and checks that a vault exists there
+func resolveVault(p *parsedArgs, positional string) (string, error) {
+	vaultPath := positional
+	if flagPath := p.value("vault"); flagPath != "" {
+		vaultPath = flagPath
+	}
+	if vaultPath == "" {
+		vaultPath = "."
+	}
+
+	// Convert to absolute path
+	absVaultPath, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault path: %w", err)
+	}
+
+	// Check if vault exists
+	if !vaultix.Exists(absVaultPath) {
+		return "", fmt.Errorf("vault not found at: %s", absVaultPath)
+	}
+
+	return absVaultPath, nil
+}
+
+// looksLikeVaultPath reports whether a positional argument names a vault
+// rather than a file inside the vault: "." or "..", a container path, or an
+// existing vault. Stored names such as certs/prod/server.pem are never taken
+// as vaults; a vault elsewhere must then be given with --vault.
+func looksLikeVaultPath(arg string) bool {
+	return arg == "." || arg == ".." ||
+		strings.EqualFold(filepath.Ext(arg), vaultix.ContainerExt) || vaultix.Exists(arg)
+}
+
+// splitVaultFileArgs interprets the positional arguments of extract-style
+// commands as [vault] [file] [output]. The first argument is only taken as the
+// vault when --vault was not given and looksLikeVaultPath accepts it.
+func splitVaultFileArgs(p *parsedArgs) (vaultPath, fileName, outputPath string) {
+	args := p.positional
+	if len(args) >= 1 && p.value("vault") == "" && looksLikeVaultPath(args[0]) {
+		vaultPath = args[0]
+		args = args[1:]
+	}
+	if len(args) >= 1 {
+		fileName = args[0]
+	}
+	if len(args) >= 2 {
+		outputPath = args[1]
+	}
+	return vaultPath, fileName, outputPath
+}
+
+// restoreFlags are the boolean flags selecting which attributes extract restores
+var restoreFlags = []string{"no-owner", "no-xattrs"}
+
+// restoreOptions returns the attribute restore options given by restoreFlags
+func restoreOptions(p *parsedArgs) vaultix.RestoreOptions {
+	return vaultix.RestoreOptions{
+		NoOwner:  p.bool("no-owner"),
+		NoXattrs: p.bool("no-xattrs"),
+	}
+}
+
+// filterValueFlags and filterBoolFlags select which files init and add -r encrypt
+var (
+	filterValueFlags = []string{"include", "exclude"}
+	filterBoolFlags  = []string{"hidden"}
+)
+
+// fileFilter returns the file filter given by the filter flags
+// --include and --exclude may be repeated.
+func fileFilter(p *parsedArgs) *vaultix.FileFilter {
+	return &vaultix.FileFilter{
+		Include: p.values["include"],
+		Exclude: p.values["exclude"],
+		Hidden:  p.bool("hidden"),
+	}
+}
+
+// hasFilterFlags reports whether any filter flag was given
+func hasFilterFlags(p *parsedArgs) bool {
+	return len(p.values["include"]) > 0 || len(p.values["exclude"]) > 0 || p.bool("hidden")
+}
+
+// splitCommand separates a command's own arguments from the command line it
+// runs, which follows the first "--"
+// Returns nil for the command line if there is no "--".
+func splitCommand(args []string) (own, command []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
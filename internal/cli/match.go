@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+	"golang.org/x/term"
+)
+
+// resolveFileName turns the name given to a command that removes files into
+// the exact name of a stored file
+// A partial or differently cased name is only used once the one file it
+// matches is confirmed on the terminal; otherwise the matches are listed and
+// nothing is removed. action describes the removal for the prompt.
+func resolveFileName(session *vaultix.Session, query, action string) (string, error) {
+	names, err := session.FindFiles(query)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(names) == 0:
+		return "", vaultix.ErrFileNotFound
+	case len(names) == 1 && names[0] == query:
+		return query, nil
+	case len(names) > 1:
+		return "", fmt.Errorf("%q matches %d files, give the full name:\n  %s",
+			query, len(names), strings.Join(names, "\n  "))
+	}
+
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("%q is not an exact name (did you mean %s?)", query, names[0])
+	}
+
+	fmt.Printf("%s %s? (yes/no): ", action, names[0])
+	var confirm string
+	fmt.Scanln(&confirm)
+	if confirm != "yes" {
+		return "", fmt.Errorf("operation cancelled")
+	}
+	return names[0], nil
+}
+
+// resolveTrashEntry turns a partial trash entry ID or file name into the ID
+// of the one entry it matches
+func resolveTrashEntry(session *vaultix.Session, query string) (string, error) {
+	entries, err := session.FindTrash(query)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(entries) {
+	case 0:
+		return "", vaultix.ErrNotInTrash
+	case 1:
+		return entries[0].ID, nil
+	default:
+		matches := make([]string, len(entries))
+		for i, entry := range entries {
+			matches[i] = fmt.Sprintf("%s  %s", entry.ID, entry.File.Name)
+		}
+		return "", fmt.Errorf("%q matches %d files in the trash, give the ID or full name:\n  %s",
+			query, len(entries), strings.Join(matches, "\n  "))
+	}
+}
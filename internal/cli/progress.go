@@ -15,6 +15,7 @@ type ProgressSpinner struct {
 	total   int
 	frames  []string
 	idx     int
+	paused  bool
 }
 
 func NewProgressSpinner(action string) *ProgressSpinner {
@@ -60,10 +61,29 @@ func (s *ProgressSpinner) Stop() {
 	close(s.done)
 }
 
+// Pause stops drawing and clears the line, so a prompt can be shown
+func (s *ProgressSpinner) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+	fmt.Printf("\r\033[K")
+}
+
+// Resume draws the spinner again after Pause
+func (s *ProgressSpinner) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
 func (s *ProgressSpinner) render() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.paused {
+		return
+	}
+
 	frame := s.frames[s.idx]
 	s.idx = (s.idx + 1) % len(s.frames)
 
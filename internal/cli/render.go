@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// Render fills a template with secrets from the vault
+func Render(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "o", "output", "mode"}, []string{"check"})
+	if err != nil {
+		return err
+	}
+	if len(p.positional) != 1 {
+		return fmt.Errorf("usage: vaultix render <template> [--vault <path>] [-o <output>] [--mode <octal>] [--check]")
+	}
+	templatePath := p.positional[0]
+
+	outputPath := p.value("output")
+	if value := p.value("o"); value != "" {
+		outputPath = value
+	}
+
+	mode := os.FileMode(0600)
+	if value := p.value("mode"); value != "" {
+		parsed, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || parsed > 0777 {
+			return fmt.Errorf("invalid mode: %s (use octal permissions such as 0640)", value)
+		}
+		if outputPath == "" {
+			return fmt.Errorf("--mode requires -o")
+		}
+		mode = os.FileMode(parsed)
+	}
+	if p.bool("check") && outputPath != "" {
+		return fmt.Errorf("--check cannot be used with -o")
+	}
+
+	// Read the template; "-" means stdin
+	var text []byte
+	name := filepath.Base(templatePath)
+	if templatePath == "-" {
+		name = "stdin"
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(templatePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	absVaultPath, err := resolveVault(p, "")
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault, reading the password from the terminal, as stdout and
+	// stdin may carry the output and template
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if p.bool("check") {
+		missing, err := session.CheckTemplate(name, string(text))
+		if err != nil {
+			return fmt.Errorf("failed to check template: %w", err)
+		}
+		if len(missing) == 0 {
+			fmt.Printf("✓ All references in %s resolve\n", templatePath)
+			return nil
+		}
+		fmt.Printf("Missing references in %s:\n", templatePath)
+		for _, ref := range missing {
+			fmt.Printf("  %s\n", ref)
+		}
+		return fmt.Errorf("%d missing reference(s)", len(missing))
+	}
+
+	if outputPath == "" {
+		if err := session.RenderTemplate(name, string(text), os.Stdout); err != nil {
+			return fmt.Errorf("failed to render template: %w", err)
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := session.RenderTemplate(name, string(text), &out); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	defer clear(out.Bytes())
+
+	// Written to a new file and renamed into place, so the output never
+	// exists with looser permissions or partial contents
+	attrs := storage.FileAttributes{Mode: mode}
+	if err := storage.WritePlaintextFile(outputPath, out.Bytes(), time.Now(), attrs, storage.RestoreOptions{NoOwner: true, NoXattrs: true}); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Rendered %s to %s (mode %04o)\n", templatePath, outputPath, mode)
+	return nil
+}
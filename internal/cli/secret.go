@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// Secret manages structured secret entries (logins, notes, API tokens)
+func Secret(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "type", "username", "url", "notes", "field", "ask", "stdin"}, nil)
+	if err != nil {
+		return err
+	}
+
+	// vaultix secret list [vault]
+	// vaultix secret <set|get|rm> <name> [vault]
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix secret <set|get|list|rm> [name] [vault]")
+	}
+	action := p.positional[0]
+	rest := p.positional[1:]
+
+	name := ""
+	switch action {
+	case "list":
+	case "set", "get", "rm":
+		if len(rest) < 1 {
+			return fmt.Errorf("usage: vaultix secret %s <name> [vault]", action)
+		}
+		name, rest = rest[0], rest[1:]
+	default:
+		return fmt.Errorf("unknown secret command: %s", action)
+	}
+
+	vaultPath := ""
+	if len(rest) >= 1 {
+		vaultPath = rest[0]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	var secretType vaultix.SecretType
+	fields := make(map[string]string)
+	if action == "set" {
+		if value := p.value("type"); value != "" {
+			if secretType, err = vaultix.ParseSecretType(value); err != nil {
+				return err
+			}
+		}
+		if err := secretFlagFields(p, fields); err != nil {
+			return err
+		}
+		if secretType == "" && len(fields) == 0 && len(p.values["ask"]) == 0 {
+			return fmt.Errorf("nothing to set; use --username, --url, --notes, --field <name>=<value>, --ask <field> or --stdin <field>")
+		}
+	}
+
+	// Unlock vault, reading the password from the terminal, leaving stdin
+	// and stdout for values
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	switch action {
+	case "set":
+		// Sensitive fields are typed without echo, so they never appear in
+		// the shell history or the process list
+		for _, field := range p.values["ask"] {
+			value, err := readPasswordFromTerminal(fmt.Sprintf("Enter %s for %s: ", field, name))
+			if err != nil {
+				return err
+			}
+			confirm, err := readPasswordFromTerminal(fmt.Sprintf("Confirm %s: ", field))
+			if err != nil {
+				return err
+			}
+			if value != confirm {
+				return fmt.Errorf("%s values do not match", field)
+			}
+			fields[field] = value
+		}
+
+		secret, err := session.SetSecret(name, secretType, fields)
+		if err != nil {
+			return fmt.Errorf("failed to set secret: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Secret saved: %s (%s; fields: %s)\n", secret.Name, secret.Type, strings.Join(secret.FieldNames, ", "))
+
+	case "get":
+		secret, values, err := session.GetSecret(name)
+		if err != nil {
+			return fmt.Errorf("failed to get secret: %w", err)
+		}
+
+		if field := p.value("field"); field != "" {
+			value, ok := values.Get(field)
+			if !ok {
+				return fmt.Errorf("secret %s has no field %s", secret.Name, field)
+			}
+			fmt.Println(value)
+			return nil
+		}
+
+		fmt.Printf("%s (%s, updated: %s)\n", secret.Name, secret.Type, secret.UpdatedAt.Format("2006-01-02 15:04:05"))
+		names := values.Names()
+		width := 0
+		for _, field := range names {
+			width = max(width, len(field)+1)
+		}
+		for _, field := range names {
+			value, _ := values.Get(field)
+			// Indent continuation lines under the first
+			value = strings.ReplaceAll(value, "\n", "\n"+strings.Repeat(" ", width+4))
+			fmt.Printf("  %-*s  %s\n", width, field+":", value)
+		}
+
+	case "list":
+		secrets, err := session.ListSecrets()
+		if err != nil {
+			return fmt.Errorf("failed to list secrets: %w", err)
+		}
+
+		if len(secrets) == 0 {
+			fmt.Println("No secrets in vault")
+			return nil
+		}
+
+		fmt.Printf("Secrets (%d):\n", len(secrets))
+		for _, secret := range secrets {
+			fmt.Printf("  %s  [%s] %s (updated: %s)\n",
+				secret.Name,
+				secret.Type,
+				strings.Join(secret.FieldNames, ", "),
+				secret.UpdatedAt.Format("2006-01-02 15:04:05"))
+		}
+
+	case "rm":
+		if err := session.RemoveSecret(name); err != nil {
+			return fmt.Errorf("failed to remove secret: %w", err)
+		}
+		fmt.Printf("✓ Secret removed: %s\n", name)
+	}
+
+	return nil
+}
+
+// secretFlagFields collects the field values given on the command line and
+// on stdin for secret set
+func secretFlagFields(p *parsedArgs, fields map[string]string) error {
+	for _, field := range []string{"username", "url", "notes"} {
+		if values := p.values[field]; len(values) > 0 {
+			fields[field] = p.value(field)
+		}
+	}
+
+	for _, assignment := range p.values["field"] {
+		field, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return fmt.Errorf("invalid --field %q (use <name>=<value>; an empty value removes the field)", assignment)
+		}
+		fields[field] = value
+	}
+
+	for _, field := range p.values["ask"] {
+		if err := vaultix.ValidateSecretField(field); err != nil {
+			return err
+		}
+	}
+
+	if field := p.value("stdin"); field != "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		fields[field] = strings.TrimRight(string(data), "\r\n")
+	}
+
+	for field := range fields {
+		if err := vaultix.ValidateSecretField(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardedSignals are passed on to a command run by exec
+var forwardedSignals = []os.Signal{syscall.SIGTERM}
+
+// terminalSignals are sent by the console to the command as well as to
+// vaultix, so they are not passed on again
+var terminalSignals = []os.Signal{os.Interrupt}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+	"golang.org/x/term"
+)
+
+// readPasswordFromTerminal reads a password from the controlling terminal,
+// prompting there too, so that stdin and stdout stay free for file contents
+func readPasswordFromTerminal(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to open terminal to read password: %w", err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty) // Print newline after password input
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
+
+// Cat decrypts a file from the vault to stdout
+func Cat(args []string) error {
+	p, err := parseArgs(args, []string{"vault", "snapshot", "version"}, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(p.positional) < 1 {
+		return fmt.Errorf("usage: vaultix cat <file> [vault-path] [--version <n>] [--snapshot <id>]")
+	}
+
+	fileName := p.positional[0]
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	opts := vaultix.ExtractOptions{Snapshot: p.value("snapshot")}
+	if value := p.value("version"); value != "" {
+		opts.Version, err = strconv.Atoi(value)
+		if err != nil || opts.Version < 1 {
+			return fmt.Errorf("invalid version: %s", value)
+		}
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if err := session.ReadFile(fileName, os.Stdout, &opts); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	return nil
+}
+
+// addStdin encrypts stdin into the vault under the name given by --name
+func addStdin(p *parsedArgs) error {
+	name := p.value("name")
+	if name == "" {
+		return fmt.Errorf("usage: vaultix add --name <stored-name> - [vault-path]")
+	}
+	if err := vaultix.ValidateFileName(name); err != nil {
+		return err
+	}
+
+	vaultPath := ""
+	if len(p.positional) >= 2 {
+		vaultPath = p.positional[1]
+	}
+
+	absVaultPath, err := resolveVault(p, vaultPath)
+	if err != nil {
+		return err
+	}
+
+	// Unlock vault
+	session, err := unlockVault(absVaultPath, readPasswordFromTerminal)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	version, err := session.AddReader(name, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to add file: %w", err)
+	}
+
+	// Report on stderr, as stdout may be piped on
+	if version > 1 {
+		fmt.Fprintf(os.Stderr, "✓ File updated: %s (version %d)\n", name, version)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "✓ File added: %s\n", name)
+	return nil
+}
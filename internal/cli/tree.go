@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+// treeNode is a directory or file in the tree view of vault contents
+type treeNode struct {
+	name     string
+	file     *vaultix.FileInfo
+	children map[string]*treeNode
+}
+
+// printTree prints the vault files as a directory tree
+func printTree(files []vaultix.FileInfo) {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for i := range files {
+		node := root
+		parts := strings.Split(files[i].Name, "/")
+		for _, part := range parts {
+			child, exists := node.children[part]
+			if !exists {
+				child = &treeNode{name: part, children: make(map[string]*treeNode)}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.file = &files[i]
+	}
+
+	root.print("  ")
+}
+
+// print writes the node's children, each line starting with indent
+func (n *treeNode) print(indent string) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := n.children[name]
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+
+		switch {
+		case child.file != nil && child.file.IsSymlink():
+			fmt.Printf("%s%s%s -> %s\n", indent, branch, name, child.file.LinkTarget)
+		case child.file != nil:
+			fmt.Printf("%s%s%s (%d bytes)\n", indent, branch, name, child.file.Size)
+		default:
+			fmt.Printf("%s%s%s/\n", indent, branch, name)
+		}
+		child.print(nextIndent)
+	}
+}
+
+// printTreePlan prints what a dry run of init or add -r found
+func printTreePlan(plan *vaultix.TreePlan) {
+	fmt.Println("Dry run: nothing has been changed")
+	fmt.Println()
+
+	if len(plan.Files) == 0 {
+		fmt.Println("No files would be encrypted")
+		return
+	}
+
+	fmt.Printf("Would encrypt and securely delete %d file(s):\n", len(plan.Files))
+	for _, f := range plan.Files {
+		fmt.Printf("  %s  (stored as %s)\n", f.Path, f.Name)
+	}
+
+	if len(plan.RemovedDirs) > 0 {
+		fmt.Println()
+		fmt.Printf("Would remove %d emptied directory(s):\n", len(plan.RemovedDirs))
+		for _, dir := range plan.RemovedDirs {
+			fmt.Printf("  %s\n", dir)
+		}
+	}
+}
@@ -3,7 +3,10 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -191,6 +194,26 @@ func DecryptMasterKeyWithRecoveryKey(encryptedMasterKey, recoveryKey []byte) ([]
 	return masterKey, nil
 }
 
+// DeriveSubkey derives a purpose-specific subkey from the master key using HKDF-SHA256
+// Different purposes always yield independent keys, so the master key itself
+// never has to be used for anything other than encryption
+func DeriveSubkey(masterKey []byte, purpose string) ([]byte, error) {
+	subkey, err := hkdf.Key(sha256.New, masterKey, nil, "vaultix "+purpose, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive subkey: %w", err)
+	}
+	return subkey, nil
+}
+
+// ComputeChunkID computes a keyed identifier for a chunk of plaintext
+// HMAC-SHA256 under a secret subkey means identical chunks get identical IDs
+// (enabling deduplication) without the ID revealing anything about the content
+func ComputeChunkID(idKey, data []byte) string {
+	mac := hmac.New(sha256.New, idKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // EncodeRecoveryKeyHex encodes a recovery key as a hexadecimal string
 // This format is easier to copy/paste and store
 func EncodeRecoveryKeyHex(recoveryKey []byte) string {
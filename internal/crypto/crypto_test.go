@@ -0,0 +1,31 @@
+package crypto
+
+import "testing"
+
+func TestComputeChunkID(t *testing.T) {
+	key, err := DeriveSubkey([]byte("0123456789abcdef0123456789abcdef"), "chunk-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := DeriveSubkey([]byte("0123456789abcdef0123456789abcdef"), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := ComputeChunkID(key, []byte("chunk"))
+	if len(id) != 64 {
+		t.Errorf("chunk ID %q is not a hex SHA-256", id)
+	}
+	if again := ComputeChunkID(key, []byte("chunk")); again != id {
+		t.Errorf("same key and data gave %s and %s", id, again)
+	}
+	if other := ComputeChunkID(key, []byte("chunk!")); other == id {
+		t.Error("different data gave the same chunk ID")
+	}
+	if other := ComputeChunkID(otherKey, []byte("chunk")); other == id {
+		t.Error("different keys gave the same chunk ID")
+	}
+	if plain := ComputeChunkID(nil, []byte("chunk")); plain == id {
+		t.Error("chunk ID does not depend on the key")
+	}
+}
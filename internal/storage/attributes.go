@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// RestoreOptions controls which file attributes are restored on extract
+type RestoreOptions struct {
+	NoOwner  bool // do not restore uid/gid (only restored when running as root)
+	NoXattrs bool // do not restore extended attributes
+}
+
+// ReadFileAttributes captures the attributes of a file for storing in the vault
+// info must come from os.Lstat so that symbolic links are described, not followed
+func ReadFileAttributes(filePath string, info os.FileInfo) (FileAttributes, error) {
+	attrs := FileAttributes{
+		Mode: info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky | os.ModeSymlink),
+	}
+
+	if uid, gid, ok := fileOwner(info); ok {
+		attrs.UID = &uid
+		attrs.GID = &gid
+	}
+
+	if attrs.IsSymlink() {
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return attrs, fmt.Errorf("failed to read symbolic link: %w", err)
+		}
+		attrs.LinkTarget = target
+	}
+
+	xattrs, err := readXattrs(filePath)
+	if err != nil {
+		return attrs, fmt.Errorf("failed to read extended attributes: %w", err)
+	}
+	attrs.Xattrs = xattrs
+
+	return attrs, nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package storage
+
+import (
+	"errors"
+	"os"
+)
+
+// fileOwner reports that ownership is not available on this platform
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// readXattrs reports no extended attributes on this platform
+func readXattrs(filePath string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// writeXattrs reports that extended attributes are not supported on this platform
+func writeXattrs(filePath string, xattrs map[string][]byte) error {
+	return errors.New("extended attributes are not supported on this platform")
+}
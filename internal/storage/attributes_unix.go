@@ -0,0 +1,84 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the uid and gid of a file
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// skipXattr reports whether an extended attribute is tied to the local system
+// (security labels, ACLs) and must not be carried over to another file
+func skipXattr(name string) bool {
+	return strings.HasPrefix(name, "security.") || strings.HasPrefix(name, "system.")
+}
+
+// readXattrs returns the extended attributes of a file, without following symbolic links
+func readXattrs(filePath string) (map[string][]byte, error) {
+	size, err := unix.Llistxattr(filePath, nil)
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(filePath, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		if name == "" || skipXattr(name) {
+			continue
+		}
+
+		valueSize, err := unix.Lgetxattr(filePath, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, valueSize)
+		valueSize, err = unix.Lgetxattr(filePath, name, value)
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = value[:valueSize]
+	}
+
+	if len(xattrs) == 0 {
+		return nil, nil
+	}
+	return xattrs, nil
+}
+
+// writeXattrs sets extended attributes on a file, without following symbolic links
+func writeXattrs(filePath string, xattrs map[string][]byte) error {
+	var failed []string
+	for name, value := range xattrs {
+		if err := unix.Lsetxattr(filePath, name, value, 0); err != nil {
+			failed = append(failed, name+": "+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, ", "))
+	}
+	return nil
+}
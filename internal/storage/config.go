@@ -0,0 +1,35 @@
+package storage
+
+// DefaultTrashRetention is how long removed files stay in the trash unless
+// the vault config says otherwise
+const DefaultTrashRetention = "30d"
+
+// VaultConfig holds per-vault settings
+// The config is kept in the encrypted metadata, so it can be neither read nor
+// changed without unlocking the vault.
+type VaultConfig struct {
+	// TrashRetention is how long removed files are kept in the trash, as a
+	// duration such as "30d", or "never" to keep them until emptied
+	TrashRetention string `json:"trash_retention,omitempty"`
+	// Wipe is how plaintext originals are destroyed after encryption
+	// (none, single or multi; see WipeMethod)
+	Wipe string `json:"wipe,omitempty"`
+}
+
+// DefaultConfig returns the settings used when a vault has no config
+func DefaultConfig() *VaultConfig {
+	return &VaultConfig{
+		TrashRetention: DefaultTrashRetention,
+		Wipe:           string(DefaultWipeMethod),
+	}
+}
+
+// ApplyDefaults fills unset values with their defaults
+func (c *VaultConfig) ApplyDefaults() {
+	if c.TrashRetention == "" {
+		c.TrashRetention = DefaultTrashRetention
+	}
+	if c.Wipe == "" {
+		c.Wipe = string(DefaultWipeMethod)
+	}
+}
@@ -0,0 +1,443 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A container is a single-file vault. It starts with a magic header followed
+// by an append-only sequence of records, each putting or deleting a named
+// entry (salt, key envelopes, metadata, objects), or appending to it. The
+// last put for a name wins, together with any appends after it. Records end
+// with a CRC32 so a torn write at the tail is detected and discarded on the
+// next append; compaction rewrites only the live entries. A damaged record
+// anywhere else makes the container unreadable rather than silently losing
+// the records after it.
+//
+// Record layout (big endian):
+//
+//	[op (1)][name length (2)][name][data length (4)][data][crc32 (4)]
+const (
+	// ContainerExt is the file extension identifying single-file vaults
+	ContainerExt = ".vtx"
+
+	containerMagic = "VAULTIX\x01"
+
+	recordPut    byte = 1
+	recordDelete byte = 2
+	recordAppend byte = 3
+
+	recordHeaderSize  = 1 + 2 + 4
+	recordTrailerSize = 4
+
+	// Compact automatically once dead records outweigh live ones in a
+	// container of at least this size
+	containerCompactMinSize = 4 * 1024 * 1024 // 4 MB
+
+	containerObjectPrefix = "objects/"
+)
+
+var (
+	ErrNotContainer       = errors.New("not a vaultix container file")
+	ErrContainerCorrupted = errors.New("container is corrupted")
+)
+
+// containerSegment locates a piece of an entry's data inside the container file
+type containerSegment struct {
+	offset int64
+	length int64
+}
+
+// containerEntry is a live entry: the data of its put record plus any appends
+type containerEntry struct {
+	segments []containerSegment
+	size     int64 // bytes taken by the entry's records, including overhead
+}
+
+// containerIndex is the in-memory view of a container's live entries
+type containerIndex struct {
+	entries map[string]containerEntry
+	size    int64       // size of the valid part of the file
+	dead    int64       // bytes taken by superseded or deleted records
+	stat    os.FileInfo // state of the file the index was built from
+}
+
+var (
+	containersMu sync.Mutex
+	containers   = make(map[string]*containerIndex)
+)
+
+// IsContainer reports whether rootPath refers to a single-file vault rather
+// than a directory: a path ending in ContainerExt, or an existing file that
+// starts with the container header
+func IsContainer(rootPath string) bool {
+	if strings.EqualFold(filepath.Ext(rootPath), ContainerExt) {
+		return true
+	}
+	return containerExists(rootPath)
+}
+
+// containerExists checks if a valid container exists at the given path
+func containerExists(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(containerMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+	return string(magic) == containerMagic
+}
+
+// createContainer writes a new, empty container file
+func createContainer(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return ErrVaultExists
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(containerMagic); err != nil {
+		return fmt.Errorf("failed to write container header: %w", err)
+	}
+	return file.Sync()
+}
+
+// loadContainer returns the index of the container, rescanning the file if it
+// changed since it was last read (caller must hold containersMu)
+func loadContainer(path string) (*containerIndex, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrVaultNotFound
+		}
+		return nil, fmt.Errorf("failed to stat container: %w", err)
+	}
+
+	if idx, ok := containers[path]; ok && idx.current(info) {
+		return idx, nil
+	}
+
+	idx, err := scanContainer(path)
+	if err != nil {
+		return nil, err
+	}
+	idx.stat = info
+	containers[path] = idx
+	return idx, nil
+}
+
+// current reports whether the file is unchanged since the index was built:
+// the same file (not one renamed over it) with the same size and mtime
+func (idx *containerIndex) current(info os.FileInfo) bool {
+	return idx.stat != nil && os.SameFile(idx.stat, info) &&
+		idx.stat.Size() == info.Size() && idx.stat.ModTime().Equal(info.ModTime())
+}
+
+// scanContainer reads every record in the container to build its index
+// Scanning stops at an incomplete or corrupted record that runs to the end of
+// the file, left by an interrupted append; one followed by more data returns
+// ErrContainerCorrupted.
+func scanContainer(path string) (*containerIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container: %w", err)
+	}
+	if len(data) < len(containerMagic) || string(data[:len(containerMagic)]) != containerMagic {
+		return nil, ErrNotContainer
+	}
+
+	idx := &containerIndex{entries: make(map[string]containerEntry)}
+	pos := int64(len(containerMagic))
+	for {
+		op, name, dataOffset, dataLength, next, ok := parseRecord(data, pos)
+		if !ok {
+			if !tornTail(data, pos) {
+				return nil, fmt.Errorf("%w: bad record at offset %d", ErrContainerCorrupted, pos)
+			}
+			break
+		}
+
+		idx.apply(op, name, dataOffset, dataLength, next-pos)
+		pos = next
+	}
+
+	idx.size = pos
+	return idx, nil
+}
+
+// apply updates the index for a record whose data starts at dataOffset
+func (idx *containerIndex) apply(op byte, name string, dataOffset, dataLength, recordSize int64) {
+	old, exists := idx.entries[name]
+	segment := containerSegment{offset: dataOffset, length: dataLength}
+
+	switch op {
+	case recordPut:
+		if exists {
+			idx.dead += old.size
+		}
+		idx.entries[name] = containerEntry{segments: []containerSegment{segment}, size: recordSize}
+	case recordAppend:
+		old.segments = append(old.segments, segment)
+		old.size += recordSize
+		idx.entries[name] = old
+	case recordDelete:
+		if exists {
+			idx.dead += old.size
+		}
+		delete(idx.entries, name)
+		idx.dead += recordSize
+	}
+}
+
+// parseRecord decodes the record starting at pos, reporting false if it is
+// incomplete or fails its checksum
+func parseRecord(data []byte, pos int64) (op byte, name string, dataOffset, dataLength, next int64, ok bool) {
+	if pos+recordHeaderSize > int64(len(data)) {
+		return 0, "", 0, 0, 0, false
+	}
+
+	op = data[pos]
+	nameLength := int64(binary.BigEndian.Uint16(data[pos+1:]))
+	nameOffset := pos + 3
+	if nameOffset+nameLength+4 > int64(len(data)) {
+		return 0, "", 0, 0, 0, false
+	}
+
+	dataLength = int64(binary.BigEndian.Uint32(data[nameOffset+nameLength:]))
+	dataOffset = nameOffset + nameLength + 4
+	next = dataOffset + dataLength + recordTrailerSize
+	if next > int64(len(data)) {
+		return 0, "", 0, 0, 0, false
+	}
+
+	sum := binary.BigEndian.Uint32(data[next-recordTrailerSize:])
+	if crc32.ChecksumIEEE(data[pos:next-recordTrailerSize]) != sum {
+		return 0, "", 0, 0, 0, false
+	}
+	if op != recordPut && op != recordDelete && op != recordAppend {
+		return 0, "", 0, 0, 0, false
+	}
+
+	return op, string(data[nameOffset : nameOffset+nameLength]), dataOffset, dataLength, next, true
+}
+
+// tornTail reports whether the record starting at pos runs to the end of the
+// file, as one cut short by an interrupted write does
+func tornTail(data []byte, pos int64) bool {
+	size := int64(len(data))
+	if pos+recordHeaderSize > size {
+		return true
+	}
+
+	nameLength := int64(binary.BigEndian.Uint16(data[pos+1:]))
+	nameOffset := pos + 3
+	if nameOffset+nameLength+4 > size {
+		return true
+	}
+
+	dataLength := int64(binary.BigEndian.Uint32(data[nameOffset+nameLength:]))
+	return nameOffset+nameLength+4+dataLength+recordTrailerSize >= size
+}
+
+// encodeRecord serializes a single record
+func encodeRecord(op byte, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(op)
+	binary.Write(&buf, binary.BigEndian, uint16(len(name)))
+	buf.WriteString(name)
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes()
+}
+
+// appendContainerRecord appends a record and updates the index
+func appendContainerRecord(path string, op byte, name string, data []byte) error {
+	if len(name) > 0xFFFF || int64(len(data)) > 0xFFFFFFFF {
+		return fmt.Errorf("container entry too large: %s", name)
+	}
+
+	containersMu.Lock()
+	defer containersMu.Unlock()
+
+	idx, err := loadContainer(path)
+	if err != nil {
+		return err
+	}
+
+	record := encodeRecord(op, name, data)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open container: %w", err)
+	}
+	defer file.Close()
+
+	// Write at the end of the valid records, overwriting any torn tail
+	if _, err := file.WriteAt(record, idx.size); err != nil {
+		return fmt.Errorf("failed to write container: %w", err)
+	}
+	if err := file.Truncate(idx.size + int64(len(record))); err != nil {
+		return fmt.Errorf("failed to write container: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync container: %w", err)
+	}
+
+	idx.apply(op, name, idx.size+recordHeaderSize+int64(len(name)), int64(len(data)), int64(len(record)))
+	idx.size += int64(len(record))
+
+	// The index now covers the write; rescan next time if the stat fails
+	idx.stat, _ = file.Stat()
+
+	if idx.size >= containerCompactMinSize && idx.dead > idx.size/2 {
+		return compactContainerLocked(path)
+	}
+	return nil
+}
+
+// readContainerEntry returns the data of a live entry
+func readContainerEntry(path, name string) ([]byte, error) {
+	containersMu.Lock()
+	defer containersMu.Unlock()
+
+	idx, err := loadContainer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, exists := idx.entries[name]
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open container: %w", err)
+	}
+	defer file.Close()
+
+	return entry.read(file)
+}
+
+// read assembles the entry's data from its segments
+func (entry containerEntry) read(file *os.File) ([]byte, error) {
+	var total int64
+	for _, segment := range entry.segments {
+		total += segment.length
+	}
+
+	data := make([]byte, total)
+	pos := int64(0)
+	for _, segment := range entry.segments {
+		if _, err := file.ReadAt(data[pos:pos+segment.length], segment.offset); err != nil {
+			return nil, fmt.Errorf("failed to read container: %w", err)
+		}
+		pos += segment.length
+	}
+	return data, nil
+}
+
+// writeContainerEntry stores data under name
+func writeContainerEntry(path, name string, data []byte) error {
+	return appendContainerRecord(path, recordPut, name, data)
+}
+
+// appendContainerEntry appends data to the named entry, creating it if needed
+func appendContainerEntry(path, name string, data []byte) error {
+	return appendContainerRecord(path, recordAppend, name, data)
+}
+
+// deleteContainerEntry removes the named entry, returning os.ErrNotExist if absent
+func deleteContainerEntry(path, name string) error {
+	containersMu.Lock()
+	idx, err := loadContainer(path)
+	if err == nil {
+		if _, exists := idx.entries[name]; !exists {
+			err = os.ErrNotExist
+		}
+	}
+	containersMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return appendContainerRecord(path, recordDelete, name, nil)
+}
+
+// CompactContainer rewrites the container keeping only live entries
+// Returns the number of bytes reclaimed
+func CompactContainer(path string) (int64, error) {
+	containersMu.Lock()
+	defer containersMu.Unlock()
+
+	idx, err := loadContainer(path)
+	if err != nil {
+		return 0, err
+	}
+	before := idx.size
+
+	if err := compactContainerLocked(path); err != nil {
+		return 0, err
+	}
+	return before - containers[path].size, nil
+}
+
+// compactContainerLocked rewrites the container (caller must hold containersMu)
+func compactContainerLocked(path string) error {
+	idx, err := loadContainer(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open container: %w", err)
+	}
+	defer src.Close()
+
+	names := make([]string, 0, len(idx.entries))
+	for name := range idx.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(containerMagic)
+	for _, name := range names {
+		data, err := idx.entries[name].read(src)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodeRecord(recordPut, name, data))
+	}
+
+	if err := writeFileAtomic(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write compacted container: %w", err)
+	}
+
+	delete(containers, path)
+	_, err = loadContainer(path)
+	return err
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestContainer creates an empty container in a temporary directory
+func newTestContainer(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test"+ContainerExt)
+	if err := createContainer(path); err != nil {
+		t.Fatalf("createContainer: %v", err)
+	}
+	return path
+}
+
+// forgetContainer drops the cached index so the next access rescans the file
+func forgetContainer(path string) {
+	containersMu.Lock()
+	delete(containers, path)
+	containersMu.Unlock()
+}
+
+// checkEntry fails the test unless the entry holds want
+func checkEntry(t *testing.T, path, name string, want []byte) {
+	t.Helper()
+	got, err := readContainerEntry(path, name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read %s = %q, want %q", name, got, want)
+	}
+}
+
+// checkMissing fails the test unless the entry is absent
+func checkMissing(t *testing.T, path, name string) {
+	t.Helper()
+	if _, err := readContainerEntry(path, name); !os.IsNotExist(err) {
+		t.Fatalf("read %s: err = %v, want not exist", name, err)
+	}
+}
+
+func TestContainerTruncatedRecord(t *testing.T) {
+	for _, cut := range []int64{1, recordTrailerSize, 10, recordHeaderSize + 12} {
+		path := newTestContainer(t)
+		if err := writeContainerEntry(path, "a", []byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeContainerEntry(path, "b", []byte("second entry")); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(path, info.Size()-cut); err != nil {
+			t.Fatal(err)
+		}
+		forgetContainer(path)
+
+		checkEntry(t, path, "a", []byte("first"))
+		checkMissing(t, path, "b")
+
+		// The next write replaces the torn tail
+		if err := writeContainerEntry(path, "c", []byte("third")); err != nil {
+			t.Fatal(err)
+		}
+		forgetContainer(path)
+		checkEntry(t, path, "a", []byte("first"))
+		checkMissing(t, path, "b")
+		checkEntry(t, path, "c", []byte("third"))
+	}
+}
+
+func TestContainerChecksumMismatch(t *testing.T) {
+	path := newTestContainer(t)
+	if err := writeContainerEntry(path, "a", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeContainerEntry(path, "b", []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeContainerEntry(path, "c", []byte("third")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A damaged record followed by valid ones is corruption, not a torn tail
+	offset := bytes.Index(data, []byte("second"))
+	data[offset] ^= 0xFF
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	forgetContainer(path)
+
+	if _, err := readContainerEntry(path, "a"); !errors.Is(err, ErrContainerCorrupted) {
+		t.Errorf("read: err = %v, want ErrContainerCorrupted", err)
+	}
+	if err := writeContainerEntry(path, "d", []byte("fourth")); !errors.Is(err, ErrContainerCorrupted) {
+		t.Errorf("write: err = %v, want ErrContainerCorrupted", err)
+	}
+	if _, err := CompactContainer(path); !errors.Is(err, ErrContainerCorrupted) {
+		t.Errorf("compact: err = %v, want ErrContainerCorrupted", err)
+	}
+
+	// Nothing was written over the records after the damage
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, data) {
+		t.Error("corrupted container was modified")
+	}
+}
+
+func TestContainerCorruptFinalRecord(t *testing.T) {
+	path := newTestContainer(t)
+	if err := writeContainerEntry(path, "a", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeContainerEntry(path, "b", []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A damaged last record reaching the end of the file is a torn write
+	offset := bytes.Index(data, []byte("second"))
+	data[offset] ^= 0xFF
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	forgetContainer(path)
+
+	checkEntry(t, path, "a", []byte("first"))
+	checkMissing(t, path, "b")
+}
+
+func TestParseRecordRejectsUnknownOp(t *testing.T) {
+	record := encodeRecord(9, "a", []byte("data"))
+	if _, _, _, _, _, ok := parseRecord(record, 0); ok {
+		t.Fatal("parseRecord accepted an unknown op")
+	}
+
+	record = encodeRecord(recordPut, "a", []byte("data"))
+	op, name, dataOffset, dataLength, next, ok := parseRecord(record, 0)
+	if !ok || op != recordPut || name != "a" || next != int64(len(record)) {
+		t.Fatalf("parseRecord = %d %q %d %v", op, name, next, ok)
+	}
+	if got := record[dataOffset : dataOffset+dataLength]; string(got) != "data" {
+		t.Fatalf("record data = %q", got)
+	}
+}
+
+func TestContainerCompactionRoundTrip(t *testing.T) {
+	path := newTestContainer(t)
+	steps := []struct {
+		op   byte
+		name string
+		data string
+	}{
+		{recordPut, "salt", "salt-1"},
+		{recordPut, "objects/x", "old"},
+		{recordPut, "objects/x", "new"},
+		{recordAppend, "metadata.log", "batch-1"},
+		{recordAppend, "metadata.log", "batch-2"},
+		{recordPut, "objects/y", "gone"},
+		{recordDelete, "objects/y", ""},
+	}
+	for _, step := range steps {
+		if err := appendContainerRecord(path, step.op, step.name, []byte(step.data)); err != nil {
+			t.Fatalf("%d %s: %v", step.op, step.name, err)
+		}
+	}
+
+	reclaimed, err := CompactContainer(path)
+	if err != nil {
+		t.Fatalf("CompactContainer: %v", err)
+	}
+	if reclaimed <= 0 {
+		t.Fatalf("reclaimed = %d, want > 0", reclaimed)
+	}
+
+	check := func() {
+		t.Helper()
+		checkEntry(t, path, "salt", []byte("salt-1"))
+		checkEntry(t, path, "objects/x", []byte("new"))
+		checkEntry(t, path, "metadata.log", []byte("batch-1batch-2"))
+		checkMissing(t, path, "objects/y")
+	}
+	check()
+
+	// The compacted file holds only live entries and reads back the same
+	forgetContainer(path)
+	check()
+	containersMu.Lock()
+	idx, err := loadContainer(path)
+	containersMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.dead != 0 || len(idx.entries) != 3 {
+		t.Fatalf("compacted index: dead = %d, entries = %d", idx.dead, len(idx.entries))
+	}
+
+	// Compacting again reclaims nothing
+	if reclaimed, err := CompactContainer(path); err != nil || reclaimed != 0 {
+		t.Fatalf("second CompactContainer = %d, %v", reclaimed, err)
+	}
+}
+
+func TestContainerCacheNoticesReplacedFile(t *testing.T) {
+	path := newTestContainer(t)
+	if err := writeContainerEntry(path, "a", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	checkEntry(t, path, "a", []byte("one"))
+
+	// Another process replaces the file with one of the same size but
+	// different entries
+	other := newTestContainer(t)
+	if err := writeContainerEntry(other, "b", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(other, path); err != nil {
+		t.Fatal(err)
+	}
+
+	checkMissing(t, path, "a")
+	checkEntry(t, path, "b", []byte("two"))
+}
+
+func TestIsContainer(t *testing.T) {
+	dir := t.TempDir()
+	container := newTestContainer(t)
+
+	renamed := filepath.Join(dir, "vault.bin")
+	if err := os.Rename(container, renamed); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(plain, []byte("just a file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "new"+ContainerExt), true},
+		{filepath.Join(dir, "NEW.VTX"), true},
+		{renamed, true},
+		{plain, false},
+		{dir, false},
+		{filepath.Join(dir, "missing"), false},
+	}
+	for _, tt := range tests {
+		if got := IsContainer(tt.path); got != tt.want {
+			t.Errorf("IsContainer(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package storage
+
+import "errors"
+
+// MakeFIFO is not supported on this platform
+func MakeFIFO(path string) error {
+	return errors.New("named pipes are not supported on this platform")
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// MakeFIFO creates a named pipe readable and writable only by the owner
+func MakeFIFO(path string) error {
+	if err := unix.Mkfifo(path, 0600); err != nil {
+		return fmt.Errorf("failed to create named pipe: %w", err)
+	}
+	return nil
+}
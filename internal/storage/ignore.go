@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the file listing patterns of files a vault never encrypts
+// It is read from the top of the directory tree being added.
+const IgnoreFileName = ".vaultixignore"
+
+// FileFilter selects which files of a directory tree are encrypted
+// Patterns use gitignore syntax and are matched against slash-separated paths
+// relative to the top of the tree.
+type FileFilter struct {
+	// Include, when set, selects only files matching one of the patterns
+	// (a pattern matching a directory selects everything inside it)
+	Include []string
+	// Exclude patterns are applied after those in .vaultixignore, so they
+	// take precedence over its ! (re-include) lines
+	Exclude []string
+	// Hidden includes dotfiles and dot-directories, which are skipped by default
+	Hidden bool
+}
+
+// ignorePattern is one compiled gitignore-style pattern
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules is an ordered list of patterns; the last one matching a path decides
+type ignoreRules []ignorePattern
+
+// parseIgnorePatterns compiles gitignore-style patterns
+// Blank lines and lines starting with # are skipped.
+func parseIgnorePatterns(lines []string) (ignoreRules, error) {
+	var rules ignoreRules
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern without a slash matches a name at any depth; one with a
+		// slash is relative to the top of the tree
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", line, err)
+		}
+		p.re = re
+		rules = append(rules, p)
+	}
+	return rules, nil
+}
+
+// globToRegexp translates a gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, end, ok := bracketExpr(glob, i)
+			if !ok {
+				// An unterminated bracket is a literal '['
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i = end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+				continue
+			}
+			b.WriteString(`\\`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// bracketExpr translates the bracket expression opening at glob[start] into a
+// regular expression class and returns the index of its closing ']'
+// A ']' first in the expression is literal, ! or ^ negates it, and POSIX
+// classes such as [:digit:] are kept. ok is false if it is never closed.
+func bracketExpr(glob string, start int) (class string, end int, ok bool) {
+	var b strings.Builder
+	b.WriteByte('[')
+
+	i := start + 1
+	negate := i < len(glob) && (glob[i] == '!' || glob[i] == '^')
+	if negate {
+		b.WriteByte('^')
+		i++
+	}
+
+	for first := true; i < len(glob); i, first = i+1, false {
+		c := glob[i]
+		switch {
+		case c == ']' && !first:
+			// Like a wildcard, a negated class never matches a slash
+			if negate {
+				b.WriteByte('/')
+			}
+			b.WriteByte(']')
+			return b.String(), i, true
+		case c == '[' && i+1 < len(glob) && glob[i+1] == ':':
+			closing := strings.Index(glob[i+2:], ":]")
+			if closing < 0 {
+				b.WriteString(classLiteral(c))
+				continue
+			}
+			b.WriteString(glob[i : i+2+closing+2])
+			i += 2 + closing + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(classLiteral(glob[i]))
+		case c == '-' && !first && i+1 < len(glob) && glob[i+1] != ']':
+			b.WriteByte('-')
+		default:
+			b.WriteString(classLiteral(c))
+		}
+	}
+	return "", 0, false
+}
+
+// classLiteral escapes a byte for use inside a regular expression class
+func classLiteral(c byte) string {
+	if c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return string(c)
+	}
+	return `\` + string(c)
+}
+
+// match reports whether a path is matched, after any ! patterns
+func (rules ignoreRules) match(relPath string, isDir bool) bool {
+	matched := false
+	for _, p := range rules {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(relPath) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+// treeFilter is a FileFilter prepared for walking one directory tree
+type treeFilter struct {
+	ignore  ignoreRules
+	include ignoreRules
+	hidden  bool
+}
+
+// newTreeFilter reads the tree's .vaultixignore and compiles the filter patterns
+func newTreeFilter(rootDir string, filter *FileFilter) (*treeFilter, error) {
+	if filter == nil {
+		filter = &FileFilter{}
+	}
+
+	lines, err := readIgnoreFile(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := parseIgnorePatterns(append(lines, filter.Exclude...))
+	if err != nil {
+		return nil, err
+	}
+	include, err := parseIgnorePatterns(filter.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	return &treeFilter{ignore: ignore, include: include, hidden: filter.Hidden}, nil
+}
+
+// readIgnoreFile returns the lines of rootDir's .vaultixignore, if it has one
+func readIgnoreFile(rootDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(rootDir, IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+
+	return strings.Split(string(data), "\n"), nil
+}
+
+// skip reports whether a file or directory is left out of the tree
+// Directories that are skipped are not descended into.
+func (f *treeFilter) skip(relPath string, isDir bool) bool {
+	name := relPath[strings.LastIndexByte(relPath, '/')+1:]
+	if name == vaultDirName || (relPath == IgnoreFileName && !isDir) {
+		return true
+	}
+	if !f.hidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	return f.ignore.match(relPath, isDir)
+}
+
+// included reports whether a file is selected by the include patterns
+// A file is selected if it, or a directory above it, matches.
+func (f *treeFilter) included(relPath string) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+
+	if f.include.match(relPath, false) {
+		return true
+	}
+	for dir := relPath; strings.Contains(dir, "/"); {
+		dir = dir[:strings.LastIndexByte(dir, '/')]
+		if f.include.match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
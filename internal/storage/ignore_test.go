@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		// Unanchored patterns match at any depth
+		{[]string{"*.log"}, "a.log", false, true},
+		{[]string{"*.log"}, "deep/dir/a.log", false, true},
+		{[]string{"*.log"}, "a.logx", false, false},
+		{[]string{"file?.txt"}, "file1.txt", false, true},
+		{[]string{"file?.txt"}, "file/.txt", false, false},
+
+		// A slash anchors the pattern to the top of the tree
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"/build"}, "src/build", true, false},
+		{[]string{"docs/*.md"}, "docs/a.md", false, true},
+		{[]string{"docs/*.md"}, "x/docs/a.md", false, false},
+		{[]string{"docs/*.md"}, "docs/sub/a.md", false, false},
+
+		// ** crosses directories
+		{[]string{"**/tmp"}, "tmp", true, true},
+		{[]string{"**/tmp"}, "a/b/tmp", true, true},
+		{[]string{"a/**/b"}, "a/b", false, true},
+		{[]string{"a/**/b"}, "a/x/y/b", false, true},
+		{[]string{"secrets/**"}, "secrets/x/y.pem", false, true},
+		{[]string{"secrets/**"}, "other/secrets/y.pem", false, false},
+
+		// A trailing slash only matches directories
+		{[]string{"logs/"}, "logs", true, true},
+		{[]string{"logs/"}, "logs", false, false},
+		{[]string{"logs/"}, "app/logs", true, true},
+
+		// The last matching pattern decides
+		{[]string{"*.env", "!keep.env"}, "keep.env", false, false},
+		{[]string{"*.env", "!keep.env"}, "drop.env", false, true},
+		{[]string{"!keep.env", "*.env"}, "keep.env", false, true},
+		{[]string{"*.env", "!keep.env", "keep.env"}, "keep.env", false, true},
+
+		// Comments, blank lines and escapes
+		{[]string{"# note.txt", "", "   "}, "# note.txt", false, false},
+		{[]string{`\#note.txt`}, "#note.txt", false, true},
+		{[]string{`\!important`}, "!important", false, true},
+		{[]string{`a\*b`}, "a*b", false, true},
+		{[]string{`a\*b`}, "axb", false, false},
+		{[]string{`a.b`}, "axb", false, false},
+
+		// Bracket expressions
+		{[]string{"[abc].txt"}, "b.txt", false, true},
+		{[]string{"[abc].txt"}, "d.txt", false, false},
+		{[]string{"[!abc].txt"}, "d.txt", false, true},
+		{[]string{"[!abc].txt"}, "a.txt", false, false},
+		{[]string{"[^abc].txt"}, "d.txt", false, true},
+		{[]string{"x[!a]y"}, "x/y", false, false},
+		{[]string{"[a-c]x"}, "bx", false, true},
+		{[]string{"[a-c]x"}, "dx", false, false},
+		{[]string{"[a-]x"}, "-x", false, true},
+		{[]string{"[]a].txt"}, "].txt", false, true},
+		{[]string{"[]a].txt"}, "a.txt", false, true},
+		{[]string{`[\]].txt`}, "].txt", false, true},
+		{[]string{"[[:digit:]].log"}, "7.log", false, true},
+		{[]string{"[[:digit:]].log"}, "a.log", false, false},
+		{[]string{"[[:digit:]].log"}, "].log", false, false},
+		{[]string{"[![:digit:]].log"}, "a.log", false, true},
+		{[]string{"[![:digit:]].log"}, "7.log", false, false},
+		{[]string{"[[:upper:]_]*"}, "_tmp", false, true},
+		{[]string{"[.*]"}, ".", false, true},
+		{[]string{"[.*]"}, "x", false, false},
+
+		// An unterminated bracket is literal
+		{[]string{"[abc"}, "[abc", false, true},
+		{[]string{"[abc"}, "a", false, false},
+		{[]string{"x[[:digit:"}, "x[[:digit:", false, true},
+	}
+	for _, tt := range tests {
+		rules, err := parseIgnorePatterns(tt.patterns)
+		if err != nil {
+			t.Errorf("parseIgnorePatterns(%q): %v", tt.patterns, err)
+			continue
+		}
+		if got := rules.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q matching %q (dir %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnorePatternsInvalid(t *testing.T) {
+	for _, pattern := range []string{"[[:nope:]]", "[z-a]"} {
+		if _, err := parseIgnorePatterns([]string{pattern}); err == nil {
+			t.Errorf("parseIgnorePatterns(%q) accepted an invalid pattern", pattern)
+		}
+	}
+}
+
+func TestTreeFilter(t *testing.T) {
+	rootDir := t.TempDir()
+	ignore := "*.bak\n!keep.bak\nbuild/\n"
+	if err := os.WriteFile(filepath.Join(rootDir, IgnoreFileName), []byte(ignore), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := newTreeFilter(rootDir, &FileFilter{
+		Include: []string{"config/", "*.env"},
+		Exclude: []string{"keep.bak"},
+	})
+	if err != nil {
+		t.Fatalf("newTreeFilter: %v", err)
+	}
+
+	skips := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"notes.txt", false, false},
+		{"old.bak", false, true},
+		{"keep.bak", false, true}, // Exclude overrides the ! line
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{".env", false, true}, // hidden
+		{".git", true, true},
+		{IgnoreFileName, false, true},
+		{vaultDirName, true, true},
+	}
+	for _, tt := range skips {
+		if got := filter.skip(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("skip(%q, dir %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	included := []struct {
+		path string
+		want bool
+	}{
+		{"config/app.yaml", true},
+		{"config/deep/db.yaml", true},
+		{"app/config/x", true},
+		{"prod.env", true},
+		{"notes.txt", false},
+		{"configs/app.yaml", false},
+	}
+	for _, tt := range included {
+		if got := filter.included(tt.path); got != tt.want {
+			t.Errorf("included(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	hidden, err := newTreeFilter(rootDir, &FileFilter{Hidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hidden.skip(".env", false) {
+		t.Error("skip(.env) with Hidden = true, want false")
+	}
+	if !hidden.skip(IgnoreFileName, false) || hidden.skip("sub/"+IgnoreFileName, false) {
+		t.Errorf("with Hidden = true, only the top-level %s is skipped", IgnoreFileName)
+	}
+	if !hidden.skip(vaultDirName, true) {
+		t.Errorf("skip(%s) with Hidden = true, want true", vaultDirName)
+	}
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// SealedMemFile returns an anonymous in-memory file holding data
+// The file is sealed, so neither its contents nor its size can change, and
+// is positioned at the start. It is closed on exec; pass it to a child process
+// through exec.Cmd.ExtraFiles. Nothing is written to any filesystem.
+func SealedMemFile(name string, data []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory file: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), name)
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write memory file: %w", err)
+	}
+
+	seals := unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seal memory file: %w", err)
+	}
+
+	// A child reading the descriptor directly starts at the beginning
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to rewind memory file: %w", err)
+	}
+
+	return file, nil
+}
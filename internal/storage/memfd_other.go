@@ -0,0 +1,13 @@
+//go:build !linux
+
+package storage
+
+import (
+	"errors"
+	"os"
+)
+
+// SealedMemFile is only supported on Linux
+func SealedMemFile(name string, data []byte) (*os.File, error) {
+	return nil, errors.New("memory files are only supported on Linux")
+}
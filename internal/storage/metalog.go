@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// The metadata log is a sequence of length-prefixed records:
+//
+//	[length (4, big endian)][record]
+//
+// Records are opaque here (the vault layer encrypts them). An incomplete
+// record at the end, left by an interrupted write, is ignored when reading
+// and overwritten by the next append.
+const metaLogFileName = "meta.log"
+
+var (
+	metaLogMu sync.Mutex
+	// metaLogValid remembers the length of the valid part of directory-layout logs
+	metaLogValid = make(map[string]int64)
+)
+
+// ReadMetadataLog returns the records of the metadata log in order
+// Returns ErrVaultNotFound if the vault has no metadata log
+func ReadMetadataLog(rootPath string) ([][]byte, error) {
+	metaLogMu.Lock()
+	defer metaLogMu.Unlock()
+
+	data, err := readVaultFile(rootPath, metaLogFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrVaultNotFound
+		}
+		return nil, fmt.Errorf("failed to read metadata log: %w", err)
+	}
+
+	records, valid := splitLogRecords(data)
+	if !IsContainer(rootPath) {
+		metaLogValid[rootPath] = valid
+	}
+	return records, nil
+}
+
+// AppendMetadataLog appends one record to the metadata log
+func AppendMetadataLog(rootPath string, record []byte) error {
+	metaLogMu.Lock()
+	defer metaLogMu.Unlock()
+
+	frame := frameLogRecord(record)
+
+	if IsContainer(rootPath) {
+		if err := appendContainerEntry(rootPath, metaLogFileName, frame); err != nil {
+			return fmt.Errorf("failed to append metadata log: %w", err)
+		}
+		return nil
+	}
+
+	valid, known := metaLogValid[rootPath]
+	if !known {
+		valid = -1
+	}
+
+	valid, err := appendLogFile(GetVaultPaths(rootPath).MetaLog, valid, frame)
+	if err != nil {
+		return fmt.Errorf("failed to append metadata log: %w", err)
+	}
+
+	metaLogValid[rootPath] = valid
+	return nil
+}
+
+// WriteMetadataLog atomically replaces the metadata log with the given records
+func WriteMetadataLog(rootPath string, records [][]byte) error {
+	metaLogMu.Lock()
+	defer metaLogMu.Unlock()
+
+	var data []byte
+	for _, record := range records {
+		data = append(data, frameLogRecord(record)...)
+	}
+
+	if IsContainer(rootPath) {
+		if err := writeContainerEntry(rootPath, metaLogFileName, data); err != nil {
+			return fmt.Errorf("failed to write metadata log: %w", err)
+		}
+		return nil
+	}
+
+	path := GetVaultPaths(rootPath).MetaLog
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write metadata log: %w", err)
+	}
+	metaLogValid[rootPath] = int64(len(data))
+	return nil
+}
+
+// DeleteMetadata removes the single-blob metadata of older vaults
+// (called once its contents have been migrated to the metadata log)
+func DeleteMetadata(rootPath string) error {
+	var err error
+	if IsContainer(rootPath) {
+		err = deleteContainerEntry(rootPath, metaFileName)
+	} else {
+		err = os.Remove(GetVaultPaths(rootPath).Meta)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+	return nil
+}
+
+// appendLogFile writes a frame after the first valid bytes of the log file,
+// discarding any torn tail (valid < 0 means the whole file is valid)
+// Returns the new length of the valid part
+func appendLogFile(path string, valid int64, frame []byte) (int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	if valid < 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		valid = info.Size()
+	}
+
+	if _, err := file.WriteAt(frame, valid); err != nil {
+		return 0, err
+	}
+	if err := file.Truncate(valid + int64(len(frame))); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+
+	return valid + int64(len(frame)), nil
+}
+
+// splitLogRecords parses length-prefixed records, stopping at an incomplete one
+// Returns the records and the length of the valid part of data
+func splitLogRecords(data []byte) ([][]byte, int64) {
+	var records [][]byte
+	pos := 0
+	for pos+4 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if pos+4+length > len(data) {
+			break
+		}
+		records = append(records, data[pos+4:pos+4+length])
+		pos += 4 + length
+	}
+	return records, int64(pos)
+}
+
+// frameLogRecord prefixes a record with its length
+func frameLogRecord(record []byte) []byte {
+	frame := make([]byte, 4+len(record))
+	binary.BigEndian.PutUint32(frame, uint32(len(record)))
+	copy(frame[4:], record)
+	return frame
+}
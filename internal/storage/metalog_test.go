@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitLogRecords(t *testing.T) {
+	one := frameLogRecord([]byte("one"))
+	two := frameLogRecord([]byte("second"))
+	full := append(append([]byte{}, one...), two...)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		want  []string
+		valid int64
+	}{
+		{"empty", nil, nil, 0},
+		{"complete", full, []string{"one", "second"}, int64(len(full))},
+		{"torn length", full[:len(one)+2], []string{"one"}, int64(len(one))},
+		{"torn record", full[:len(full)-1], []string{"one"}, int64(len(one))},
+		{"length past end", append(append([]byte{}, one...), 0, 0, 1, 0, 'x'), []string{"one"}, int64(len(one))},
+	}
+	for _, tt := range tests {
+		records, valid := splitLogRecords(tt.data)
+		if valid != tt.valid {
+			t.Errorf("%s: valid = %d, want %d", tt.name, valid, tt.valid)
+		}
+		if len(records) != len(tt.want) {
+			t.Errorf("%s: %d records, want %d", tt.name, len(records), len(tt.want))
+			continue
+		}
+		for i, record := range records {
+			if string(record) != tt.want[i] {
+				t.Errorf("%s: record %d = %q, want %q", tt.name, i, record, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestMetadataLogAppendAfterTornRecord(t *testing.T) {
+	for _, layout := range []string{"directory", "container"} {
+		rootPath := t.TempDir()
+		if layout == "container" {
+			rootPath = filepath.Join(rootPath, "vault"+ContainerExt)
+		}
+		if err := InitializeVault(rootPath); err != nil {
+			t.Fatalf("%s: InitializeVault: %v", layout, err)
+		}
+		if err := WriteMetadataLog(rootPath, [][]byte{[]byte("one")}); err != nil {
+			t.Fatalf("%s: WriteMetadataLog: %v", layout, err)
+		}
+		if err := AppendMetadataLog(rootPath, []byte("two")); err != nil {
+			t.Fatalf("%s: AppendMetadataLog: %v", layout, err)
+		}
+
+		// An interrupted append leaves a partial record at the end: of the
+		// log file, or of the container holding the log
+		path := GetVaultPaths(rootPath).MetaLog
+		torn := frameLogRecord([]byte("three"))
+		if layout == "container" {
+			path = rootPath
+			torn = encodeRecord(recordAppend, metaLogFileName, torn)
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(torn[:len(torn)-3]); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		checkLog := func(want ...string) {
+			t.Helper()
+			records, err := ReadMetadataLog(rootPath)
+			if err != nil {
+				t.Fatalf("%s: ReadMetadataLog: %v", layout, err)
+			}
+			got := bytes.Join(records, []byte(","))
+			if string(got) != strings.Join(want, ",") {
+				t.Fatalf("%s: records = %q, want %q", layout, got, strings.Join(want, ","))
+			}
+		}
+		checkLog("one", "two")
+
+		// The next append replaces the torn record
+		if err := AppendMetadataLog(rootPath, []byte("four")); err != nil {
+			t.Fatalf("%s: AppendMetadataLog: %v", layout, err)
+		}
+		if layout == "directory" {
+			metaLogMu.Lock()
+			delete(metaLogValid, rootPath)
+			metaLogMu.Unlock()
+		}
+		checkLog("one", "two", "four")
+	}
+}
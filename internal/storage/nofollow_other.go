@@ -0,0 +1,6 @@
+//go:build !linux && !darwin
+
+package storage
+
+// noFollowFlags is empty on this platform; the opened file is still checked
+const noFollowFlags = 0
@@ -0,0 +1,9 @@
+//go:build linux || darwin
+
+package storage
+
+import "golang.org/x/sys/unix"
+
+// noFollowFlags make opening a path fail if it is a symbolic link, and
+// keep a named pipe put in its place from blocking the open
+const noFollowFlags = unix.O_NOFOLLOW | unix.O_NONBLOCK
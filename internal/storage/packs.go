@@ -0,0 +1,573 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+)
+
+const (
+	packsDirName      = "packs"
+	packIndexFileName = "index"
+	packFileExt       = ".pack"
+
+	// Objects up to this size are stored in pack files instead of loose files
+	packObjectMaxSize = 256 * 1024 // 256 KB
+	// New objects are appended to an existing pack until it reaches this size
+	packTargetSize = 16 * 1024 * 1024 // 16 MB
+
+	// Compact the index log once it holds this many more entries than live ones
+	packIndexCompactSlack = 256
+)
+
+// packLocation locates an object inside a pack file
+type packLocation struct {
+	Pack   string `json:"pack"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+}
+
+// packInfo tracks the size of a pack file and how much of it is no longer referenced
+type packInfo struct {
+	Size int64 `json:"size"`
+	Dead int64 `json:"dead"`
+}
+
+// packIndex maps object IDs to their location in pack files
+// The index is stored encrypted, so neither object count nor object sizes
+// can be read from the vault directory
+type packIndex struct {
+	Packs   map[string]*packInfo
+	Objects map[string]packLocation
+}
+
+// packIndexDelta is one record of the on-disk pack index log, which uses the
+// same framing as the metadata log. A nil value marks a removal; a compacted
+// log holds a single delta with every entry.
+type packIndexDelta struct {
+	Packs   map[string]*packInfo     `json:"packs,omitempty"`
+	Objects map[string]*packLocation `json:"objects,omitempty"`
+}
+
+// Packs is the unlocked pack index of one vault, opened with OpenPacks
+// Objects written are recorded in memory and the index is saved by Flush, so
+// an operation storing many objects rewrites the index once. Close zeroes the
+// index key.
+type Packs struct {
+	mu       sync.Mutex
+	rootPath string
+	key      []byte
+	index    *packIndex
+
+	// Entries changed since the index was last saved
+	dirtyPacks   map[string]bool
+	dirtyObjects map[string]bool
+	// emptied holds packs with no live objects, removed once the index is saved
+	emptied []string
+
+	indexValid   int64 // length of the valid part of the index log
+	indexEntries int   // entries in the index log, live or superseded
+}
+
+// RepackResult reports what a repack did
+type RepackResult struct {
+	PacksRewritten int   // packs that were compacted away
+	ObjectsPacked  int   // loose objects moved into packs
+	BytesReclaimed int64 // unreferenced bytes removed from packs
+}
+
+// OpenPacks enables pack storage for the vault using the given index key,
+// which is copied
+// Until this is called only loose objects can be read or written.
+func OpenPacks(rootPath string, indexKey []byte) *Packs {
+	return &Packs{rootPath: rootPath, key: append([]byte(nil), indexKey...)}
+}
+
+// Close zeroes the index key and drops the cached index
+// Changes not yet saved by Flush are lost.
+func (ps *Packs) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	clear(ps.key)
+	ps.key = nil
+	ps.reset()
+}
+
+// Refresh drops the cached index so that the next access reads changes made
+// by other processes; it does nothing while changes are waiting for Flush
+func (ps *Packs) Refresh() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if len(ps.dirtyPacks) == 0 && len(ps.dirtyObjects) == 0 {
+		ps.reset()
+	}
+}
+
+// Flush saves the index changes made since the last Flush
+// Must be called before metadata referring to newly written objects is
+// committed. On failure the cached index is dropped and reloaded from disk.
+func (ps *Packs) Flush() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.index == nil {
+		return nil
+	}
+	if err := ps.saveIndex(); err != nil {
+		ps.reset()
+		return err
+	}
+	return nil
+}
+
+// reset drops the cached index and its unsaved changes (caller must hold ps.mu)
+func (ps *Packs) reset() {
+	ps.index = nil
+	ps.dirtyPacks = nil
+	ps.dirtyObjects = nil
+	ps.emptied = nil
+}
+
+// packsDir returns the directory holding pack files and the pack index
+func packsDir(rootPath string) string {
+	return filepath.Join(GetVaultPaths(rootPath).Objects, packsDirName)
+}
+
+// packIndexPath returns the path of the pack index log
+func packIndexPath(rootPath string) string {
+	return filepath.Join(packsDir(rootPath), packIndexFileName)
+}
+
+// packPath returns the path of a pack file
+func packPath(rootPath, packID string) string {
+	return filepath.Join(packsDir(rootPath), packID+packFileExt)
+}
+
+// loadIndex reads and decrypts the pack index log (caller must hold ps.mu)
+func (ps *Packs) loadIndex() (*packIndex, error) {
+	if ps.index != nil {
+		return ps.index, nil
+	}
+
+	index := &packIndex{
+		Packs:   make(map[string]*packInfo),
+		Objects: make(map[string]packLocation),
+	}
+	ps.dirtyPacks = make(map[string]bool)
+	ps.dirtyObjects = make(map[string]bool)
+	ps.indexValid = 0
+	ps.indexEntries = 0
+
+	data, err := os.ReadFile(packIndexPath(ps.rootPath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pack index: %w", err)
+	}
+
+	records, valid := splitLogRecords(data)
+	for _, record := range records {
+		plain, err := crypto.Decrypt(record, ps.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt pack index: %w", err)
+		}
+
+		var delta packIndexDelta
+		if err := json.Unmarshal(plain, &delta); err != nil {
+			return nil, fmt.Errorf("failed to parse pack index: %w", err)
+		}
+		for id, info := range delta.Packs {
+			if info == nil {
+				delete(index.Packs, id)
+			} else {
+				index.Packs[id] = info
+			}
+		}
+		for id, loc := range delta.Objects {
+			if loc == nil {
+				delete(index.Objects, id)
+			} else {
+				index.Objects[id] = *loc
+			}
+		}
+		ps.indexEntries += len(delta.Packs) + len(delta.Objects)
+	}
+
+	ps.index = index
+	ps.indexValid = valid
+	return index, nil
+}
+
+// saveIndex appends the changed index entries to the index log, compacting
+// the log once it is mostly superseded entries (caller must hold ps.mu)
+func (ps *Packs) saveIndex() error {
+	if len(ps.dirtyPacks) == 0 && len(ps.dirtyObjects) == 0 {
+		return nil
+	}
+
+	live := len(ps.index.Packs) + len(ps.index.Objects)
+	changed := len(ps.dirtyPacks) + len(ps.dirtyObjects)
+	compact := ps.indexEntries+changed > 2*live+packIndexCompactSlack
+
+	delta := packIndexDelta{
+		Packs:   make(map[string]*packInfo),
+		Objects: make(map[string]*packLocation),
+	}
+	if compact {
+		for id, info := range ps.index.Packs {
+			delta.Packs[id] = info
+		}
+		for id, loc := range ps.index.Objects {
+			loc := loc
+			delta.Objects[id] = &loc
+		}
+	} else {
+		for id := range ps.dirtyPacks {
+			delta.Packs[id] = ps.index.Packs[id]
+		}
+		for id := range ps.dirtyObjects {
+			if loc, exists := ps.index.Objects[id]; exists {
+				delta.Objects[id] = &loc
+			} else {
+				delta.Objects[id] = nil
+			}
+		}
+	}
+
+	plain, err := json.Marshal(delta)
+	if err != nil {
+		return fmt.Errorf("failed to serialize pack index: %w", err)
+	}
+	record, err := crypto.Encrypt(plain, ps.key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt pack index: %w", err)
+	}
+
+	if compact {
+		frame := frameLogRecord(record)
+		if err := writeFileAtomic(packIndexPath(ps.rootPath), frame, 0600); err != nil {
+			return fmt.Errorf("failed to write pack index: %w", err)
+		}
+		ps.indexValid = int64(len(frame))
+		ps.indexEntries = live
+	} else {
+		valid, err := appendLogFile(packIndexPath(ps.rootPath), ps.indexValid, frameLogRecord(record))
+		if err != nil {
+			return fmt.Errorf("failed to write pack index: %w", err)
+		}
+		ps.indexValid = valid
+		ps.indexEntries += changed
+	}
+	ps.dirtyPacks = make(map[string]bool)
+	ps.dirtyObjects = make(map[string]bool)
+
+	// The index no longer references emptied packs, so they can go
+	for _, packID := range ps.emptied {
+		os.Remove(packPath(ps.rootPath, packID))
+	}
+	ps.emptied = nil
+	return nil
+}
+
+// writePacked appends an object to the current pack and records it in the
+// index in memory; the index is saved by Flush
+func (ps *Packs) writePacked(objectID string, data []byte) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	return ps.appendObject(index, objectID, data)
+}
+
+// appendObject writes data to a pack with room left, creating one if needed
+// The index is updated in memory only (caller must hold ps.mu and save the index)
+func (ps *Packs) appendObject(index *packIndex, objectID string, data []byte) error {
+	// Pick a pack that still has room
+	packID := ""
+	for id, info := range index.Packs {
+		if info.Size+int64(len(data)) <= packTargetSize && (packID == "" || id < packID) {
+			packID = id
+		}
+	}
+	if packID == "" {
+		id, err := newPackID()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(packsDir(ps.rootPath), 0700); err != nil {
+			return fmt.Errorf("failed to create packs directory: %w", err)
+		}
+		packID = id
+		index.Packs[packID] = &packInfo{}
+		ps.dirtyPacks[packID] = true
+	}
+
+	info := index.Packs[packID]
+	file, err := os.OpenFile(packPath(ps.rootPath, packID), os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open pack: %w", err)
+	}
+	defer file.Close()
+
+	// Write at the recorded end, discarding any bytes from an interrupted write
+	if _, err := file.WriteAt(data, info.Size); err != nil {
+		return fmt.Errorf("failed to write pack: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync pack: %w", err)
+	}
+
+	// Replacing an existing object leaves its old copy as dead space
+	if old, exists := index.Objects[objectID]; exists {
+		ps.markDead(index, old)
+	}
+
+	index.Objects[objectID] = packLocation{Pack: packID, Offset: info.Size, Length: int64(len(data))}
+	info.Size += int64(len(data))
+	ps.dirtyObjects[objectID] = true
+	ps.dirtyPacks[packID] = true
+	return nil
+}
+
+// readPacked reads an object from its pack, returning ErrFileNotInVault if it is not packed
+func (ps *Packs) readPacked(objectID string) ([]byte, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	loc, exists := index.Objects[objectID]
+	if !exists {
+		return nil, ErrFileNotInVault
+	}
+
+	file, err := os.Open(packPath(ps.rootPath, loc.Pack))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pack: %w", err)
+	}
+	defer file.Close()
+
+	data := make([]byte, loc.Length)
+	if _, err := file.ReadAt(data, loc.Offset); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("failed to read object: pack is truncated")
+		}
+		return nil, fmt.Errorf("failed to read pack: %w", err)
+	}
+	return data, nil
+}
+
+// deletePacked removes an object from the index in memory, returning
+// ErrFileNotInVault if it is not packed; the index is saved by Flush
+func (ps *Packs) deletePacked(objectID string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	loc, exists := index.Objects[objectID]
+	if !exists {
+		return ErrFileNotInVault
+	}
+
+	delete(index.Objects, objectID)
+	ps.dirtyObjects[objectID] = true
+	ps.markDead(index, loc)
+	return nil
+}
+
+// markDead accounts for an unreferenced object in a pack, scheduling the pack
+// file for removal once nothing in it is referenced any more
+func (ps *Packs) markDead(index *packIndex, loc packLocation) {
+	info, exists := index.Packs[loc.Pack]
+	if !exists {
+		return
+	}
+
+	info.Dead += loc.Length
+	ps.dirtyPacks[loc.Pack] = true
+	if info.Dead >= info.Size {
+		delete(index.Packs, loc.Pack)
+		ps.emptied = append(ps.emptied, loc.Pack)
+	}
+}
+
+// Repack compacts pack files that contain unreferenced data and moves small
+// loose objects into packs. Container vaults have no packs; their records are
+// compacted instead. If the repack fails the cached index is reloaded, so
+// nothing is left pointing at packs that were detached.
+func Repack(rootPath string, ps *Packs) (result *RepackResult, err error) {
+	if IsContainer(rootPath) {
+		reclaimed, err := CompactContainer(rootPath)
+		if err != nil {
+			return nil, err
+		}
+		return &RepackResult{PacksRewritten: 1, BytesReclaimed: reclaimed}, nil
+	}
+
+	if ps == nil {
+		return nil, fmt.Errorf("pack storage is not open")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			ps.reset()
+		}
+	}()
+
+	// Save earlier changes first so that a failure below only loses the repack
+	if err := ps.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	result = &RepackResult{}
+
+	// Detach packs with dead space so their live objects get appended elsewhere
+	var oldPacks []string
+	for id, info := range index.Packs {
+		if info.Dead > 0 {
+			oldPacks = append(oldPacks, id)
+			result.BytesReclaimed += info.Dead
+			delete(index.Packs, id)
+			ps.dirtyPacks[id] = true
+		}
+	}
+	sort.Strings(oldPacks)
+
+	for _, packID := range oldPacks {
+		data, err := os.ReadFile(packPath(rootPath, packID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pack: %w", err)
+		}
+
+		var objectIDs []string
+		for id, loc := range index.Objects {
+			if loc.Pack == packID {
+				objectIDs = append(objectIDs, id)
+			}
+		}
+		sort.Slice(objectIDs, func(i, j int) bool {
+			return index.Objects[objectIDs[i]].Offset < index.Objects[objectIDs[j]].Offset
+		})
+
+		for _, id := range objectIDs {
+			loc := index.Objects[id]
+			if loc.Offset+loc.Length > int64(len(data)) {
+				return nil, fmt.Errorf("failed to repack: pack is truncated")
+			}
+			delete(index.Objects, id)
+			ps.dirtyObjects[id] = true
+			if err := ps.appendObject(index, id, data[loc.Offset:loc.Offset+loc.Length]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.PacksRewritten = len(oldPacks)
+
+	// Move small loose objects into packs
+	entries, err := os.ReadDir(GetVaultPaths(rootPath).Objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objects directory: %w", err)
+	}
+
+	var packedLoose []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".enc") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > packObjectMaxSize {
+			continue
+		}
+
+		objectID := strings.TrimSuffix(entry.Name(), ".enc")
+		data, err := os.ReadFile(GetObjectPath(rootPath, objectID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object: %w", err)
+		}
+		if err := ps.appendObject(index, objectID, data); err != nil {
+			return nil, err
+		}
+		packedLoose = append(packedLoose, objectID)
+	}
+
+	// Persist the new index before deleting anything it no longer points to
+	if err := ps.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	for _, packID := range oldPacks {
+		os.Remove(packPath(rootPath, packID))
+	}
+	for _, objectID := range packedLoose {
+		os.Remove(GetObjectPath(rootPath, objectID))
+	}
+	result.ObjectsPacked = len(packedLoose)
+
+	return result, nil
+}
+
+// newPackID generates a random pack identifier
+func newPackID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", fmt.Errorf("failed to generate pack ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
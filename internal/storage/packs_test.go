@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestPacks creates a directory vault and opens its pack store
+func newTestPacks(t *testing.T) (string, []byte, *Packs) {
+	t.Helper()
+	rootPath := t.TempDir()
+	if err := InitializeVault(rootPath); err != nil {
+		t.Fatalf("InitializeVault: %v", err)
+	}
+	key := bytes.Repeat([]byte{7}, 32)
+	ps := OpenPacks(rootPath, key)
+	t.Cleanup(ps.Close)
+	return rootPath, key, ps
+}
+
+// writeObjects stores each object in packs and saves the index
+func writeObjects(t *testing.T, rootPath string, ps *Packs, objects map[string]string) {
+	t.Helper()
+	for id, data := range objects {
+		if err := WriteObject(rootPath, ps, id, []byte(data)); err != nil {
+			t.Fatalf("WriteObject %s: %v", id, err)
+		}
+	}
+	if err := ps.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
+
+// checkObject fails the test unless the object holds want
+func checkObject(t *testing.T, rootPath string, ps *Packs, id, want string) {
+	t.Helper()
+	got, err := ReadObject(rootPath, ps, id)
+	if err != nil {
+		t.Fatalf("ReadObject %s: %v", id, err)
+	}
+	if string(got) != want {
+		t.Fatalf("ReadObject %s = %q, want %q", id, got, want)
+	}
+}
+
+// checkNoObject fails the test unless the object is not stored
+func checkNoObject(t *testing.T, rootPath string, ps *Packs, id string) {
+	t.Helper()
+	if _, err := ReadObject(rootPath, ps, id); err != ErrFileNotInVault {
+		t.Fatalf("ReadObject %s: err = %v, want ErrFileNotInVault", id, err)
+	}
+}
+
+// packFiles returns the pack files in the vault
+func packFiles(t *testing.T, rootPath string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(packsDir(rootPath), "*"+packFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestPacksFlushAndReopen(t *testing.T) {
+	rootPath, key, ps := newTestPacks(t)
+	writeObjects(t, rootPath, ps, map[string]string{"aa": "first", "bb": "second"})
+
+	// A change that is never flushed is lost with the handle
+	if err := WriteObject(rootPath, ps, "cc", []byte("unsaved")); err != nil {
+		t.Fatal(err)
+	}
+	ps.Close()
+
+	reopened := OpenPacks(rootPath, key)
+	defer reopened.Close()
+	checkObject(t, rootPath, reopened, "aa", "first")
+	checkObject(t, rootPath, reopened, "bb", "second")
+	checkNoObject(t, rootPath, reopened, "cc")
+
+	// The index cannot be read with another key
+	wrongKey := OpenPacks(rootPath, bytes.Repeat([]byte{8}, 32))
+	defer wrongKey.Close()
+	if _, err := ReadObject(rootPath, wrongKey, "aa"); err == nil {
+		t.Error("pack index decrypted with the wrong key")
+	}
+}
+
+func TestPacksDeleteAndRepack(t *testing.T) {
+	rootPath, key, ps := newTestPacks(t)
+	writeObjects(t, rootPath, ps, map[string]string{"aa": "first", "bb": "second", "cc": "third"})
+
+	if err := DeleteObject(rootPath, ps, "bb"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	before := packFiles(t, rootPath)
+
+	result, err := Repack(rootPath, ps)
+	if err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+	if result.BytesReclaimed == 0 {
+		t.Error("Repack reclaimed nothing after a delete")
+	}
+	after := packFiles(t, rootPath)
+	if len(after) != 1 || after[0] == before[0] {
+		t.Errorf("pack files %v after repacking %v, want one new pack", after, before)
+	}
+
+	reopened := OpenPacks(rootPath, key)
+	defer reopened.Close()
+	checkObject(t, rootPath, reopened, "aa", "first")
+	checkObject(t, rootPath, reopened, "cc", "third")
+	checkNoObject(t, rootPath, reopened, "bb")
+}
+
+func TestPacksTornIndexTail(t *testing.T) {
+	rootPath, key, ps := newTestPacks(t)
+	writeObjects(t, rootPath, ps, map[string]string{"aa": "first"})
+	writeObjects(t, rootPath, ps, map[string]string{"bb": "second"})
+	ps.Close()
+
+	// Cut the last index record short, as an interrupted write would
+	info, err := os.Stat(packIndexPath(rootPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(packIndexPath(rootPath), info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := OpenPacks(rootPath, key)
+	defer reopened.Close()
+	checkObject(t, rootPath, reopened, "aa", "first")
+	checkNoObject(t, rootPath, reopened, "bb")
+
+	// The next save replaces the torn record
+	writeObjects(t, rootPath, reopened, map[string]string{"cc": "third"})
+	final := OpenPacks(rootPath, key)
+	defer final.Close()
+	checkObject(t, rootPath, final, "aa", "first")
+	checkObject(t, rootPath, final, "cc", "third")
+	checkNoObject(t, rootPath, final, "bb")
+}
+
+func TestPacksFailedRepackReloadsIndex(t *testing.T) {
+	rootPath, _, ps := newTestPacks(t)
+	writeObjects(t, rootPath, ps, map[string]string{"aa": "first", "bb": "second"})
+	if err := DeleteObject(rootPath, ps, "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the pack unreadable so the repack fails after detaching it
+	pack := packFiles(t, rootPath)[0]
+	if err := os.Rename(pack, pack+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(pack, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Repack(rootPath, ps); err == nil {
+		t.Fatal("Repack succeeded with an unreadable pack")
+	}
+	if err := os.Remove(pack); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(pack+".moved", pack); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same handle sees the index on disk again, dead space included
+	checkObject(t, rootPath, ps, "bb", "second")
+	result, err := Repack(rootPath, ps)
+	if err != nil {
+		t.Fatalf("Repack: %v", err)
+	}
+	if result.BytesReclaimed == 0 {
+		t.Error("Repack after a failed one reclaimed nothing")
+	}
+	checkObject(t, rootPath, ps, "bb", "second")
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned for file names and destinations that extraction
+// refuses, because they could write outside the destination directory
+var ErrUnsafePath = errors.New("unsafe path")
+
+// maxFileNameLength bounds a stored file name, including its directories
+const maxFileNameLength = 4096
+
+// ValidateFileName checks a stored file name against the naming policy
+// A name is a relative, slash-separated path: it must not be absolute or carry
+// a volume name, and must not contain empty, "." or ".." elements, backslashes
+// or control characters.
+func ValidateFileName(name string) error {
+	reject := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrUnsafePath, name, reason)
+	}
+
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty file name", ErrUnsafePath)
+	case len(name) > maxFileNameLength:
+		return reject("name is too long")
+	case strings.HasPrefix(name, "/"):
+		return reject("absolute path")
+	case strings.Contains(name, `\`):
+		return reject("contains a backslash")
+	case filepath.VolumeName(filepath.FromSlash(name)) != "" || filepath.IsAbs(filepath.FromSlash(name)):
+		return reject("absolute path")
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return reject("contains a control character")
+		}
+	}
+
+	for _, element := range strings.Split(name, "/") {
+		switch element {
+		case "":
+			return reject("contains an empty path element")
+		case ".", "..":
+			return reject(fmt.Sprintf("contains a %q element", element))
+		}
+	}
+
+	return nil
+}
+
+// SafeJoin returns the path of a stored file name beneath root ("" means the
+// current directory), creating missing parent directories
+// The name is checked with ValidateFileName, and no directory between root and
+// the file may be a symbolic link, so the path cannot resolve outside root.
+func SafeJoin(root, name string) (string, error) {
+	if err := ValidateFileName(name); err != nil {
+		return "", err
+	}
+	if root == "" {
+		root = "."
+	}
+
+	parts := strings.Split(name, "/")
+	dir := root
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+				return "", fmt.Errorf("failed to create directory: %w", err)
+			}
+			// Check what is there now, in case it was created concurrently
+			info, err = os.Lstat(dir)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check %s: %w", dir, err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w %q: %s is a symbolic link", ErrUnsafePath, name, dir)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("%w %q: %s is not a directory", ErrUnsafePath, name, dir)
+		}
+	}
+
+	return filepath.Join(root, filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"notes.txt", true},
+		{"certs/prod/server.pem", true},
+		{"..hidden", true},
+		{"a..b/c", true},
+		{"unicode/ünïcödé.txt", true},
+		{"", false},
+		{"..", false},
+		{".", false},
+		{"../etc/passwd", false},
+		{"a/../../b", false},
+		{"a/..", false},
+		{"./a", false},
+		{"a//b", false},
+		{"a/", false},
+		{"/etc/passwd", false},
+		{"//server/share", false},
+		{`a\b`, false},
+		{`..\..\evil`, false},
+		{`C:\evil`, false},
+		{"a\x00b", false},
+		{"a\nb", false},
+		{"tab\there", false},
+		{"bell\x07", false},
+		{"del\x7f", false},
+		{strings.Repeat("a", maxFileNameLength+1), false},
+	}
+	for _, tt := range tests {
+		err := ValidateFileName(tt.name)
+		if tt.ok && err != nil {
+			t.Errorf("ValidateFileName(%q) = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("ValidateFileName(%q) = %v, want ErrUnsafePath", tt.name, err)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	// A symbolic link pointing outside the root, and one pointing back inside
+	if err := os.Symlink(outside, filepath.Join(root, "out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "real"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "in")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string // path below root, or "" if refused
+	}{
+		{"a.txt", "a.txt"},
+		{"new/dir/a.txt", "new/dir/a.txt"},
+		{"real/a.txt", "real/a.txt"},
+		{"out", "out"}, // the link itself is replaced on extraction, never followed
+		{"out/a.txt", ""},
+		{"out/sub/a.txt", ""},
+		{"in/a.txt", ""},
+		{"file/a.txt", ""},
+		{"../a.txt", ""},
+		{"/tmp/a.txt", ""},
+		{`sub\a.txt`, ""},
+		{"sub/\x00.txt", ""},
+		{"sub/\x1b[31m.txt", ""},
+	}
+	for _, tt := range tests {
+		got, err := SafeJoin(root, tt.name)
+		if tt.want == "" {
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("SafeJoin(%q) = %q, %v; want ErrUnsafePath", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SafeJoin(%q): %v", tt.name, err)
+			continue
+		}
+		if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+			t.Errorf("SafeJoin(%q) = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	// Nothing was created through the links
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("SafeJoin created %d entries outside the root", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(root, "new", "dir")); err != nil {
+		t.Errorf("parent directories were not created: %v", err)
+	}
+}
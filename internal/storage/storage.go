@@ -1,14 +1,15 @@
 package storage
 
 import (
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -16,7 +17,6 @@ const (
 	vaultDirName        = ".vaultix"
 	metaFileName        = "meta"
 	saltFileName        = "salt"
-	configFileName      = "config"
 	objectsDirName      = "objects"
 	masterKeyFileName   = "master.key"
 	recoveryKeyFileName = "recovery.key"
@@ -33,8 +33,8 @@ type VaultPaths struct {
 	Root        string
 	VaultDir    string
 	Meta        string
+	MetaLog     string
 	Salt        string
-	Config      string
 	Objects     string
 	MasterKey   string
 	RecoveryKey string
@@ -47,12 +47,200 @@ type FileMetadata struct {
 	Size         int64     `json:"size"`
 	ModTime      time.Time `json:"mod_time"`
 	AddedAt      time.Time `json:"added_at"`
+	// Chunks lists the content-defined chunks making up the file, in order.
+	// Files added before chunking was introduced have no chunks in version 1
+	// and 2 metadata; their single object becomes their only chunk when the
+	// metadata is migrated.
+	Chunks []string `json:"chunks,omitempty"`
+	// SHA256 is the hex SHA-256 digest of the plaintext, checked after every
+	// decryption. Files added before digests were recorded have none.
+	SHA256 string `json:"sha256,omitempty"`
+	FileAttributes
+	// Version is the number of the current contents (0 in older metadata
+	// means 1), UpdatedAt when they were stored. Versions holds the earlier
+	// contents still kept, oldest first.
+	Version   int           `json:"version,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at,omitempty"`
+	Versions  []FileVersion `json:"versions,omitempty"`
+}
+
+// FileVersion stores an earlier version of a file's contents
+type FileVersion struct {
+	Version int       `json:"version"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	AddedAt time.Time `json:"added_at"`
+	Chunks  []string  `json:"chunks"`
+	SHA256  string    `json:"sha256,omitempty"`
+	FileAttributes
+}
+
+// FileAttributes stores the file system attributes captured when a file is
+// added, so they can be restored on extract. Files added by older versions
+// have none (Mode 0).
+type FileAttributes struct {
+	// Mode holds the permission bits, setuid/setgid/sticky bits and, for
+	// symbolic links, os.ModeSymlink
+	Mode os.FileMode `json:"mode,omitempty"`
+	// UID and GID are nil where ownership is not available (e.g. Windows)
+	UID        *int              `json:"uid,omitempty"`
+	GID        *int              `json:"gid,omitempty"`
+	Xattrs     map[string][]byte `json:"xattrs,omitempty"`
+	LinkTarget string            `json:"link_target,omitempty"`
+}
+
+// IsSymlink reports whether the attributes describe a symbolic link
+func (a *FileAttributes) IsSymlink() bool {
+	return a.Mode&os.ModeSymlink != 0
+}
+
+// ChunkMetadata stores information about a deduplicated chunk object
+type ChunkMetadata struct {
+	Size     int64 `json:"size"`
+	RefCount int   `json:"ref_count"`
+}
+
+// SnapshotMetadata describes a saved, immutable view of the vault's files
+// The file list itself is kept in an encrypted object
+type SnapshotMetadata struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	FileCount   int       `json:"file_count"`
+	TotalSize   int64     `json:"total_size"`
+	ObjectID    string    `json:"object_id"`
+}
+
+// TrashEntry is a removed file kept until the trash expires or is emptied
+type TrashEntry struct {
+	ID        string       `json:"id"`
+	DeletedAt time.Time    `json:"deleted_at"`
+	File      FileMetadata `json:"file"`
+}
+
+// SecretType classifies a structured secret entry
+type SecretType string
+
+const (
+	SecretLogin SecretType = "login" // username, password and URL
+	SecretNote  SecretType = "note"  // free text in the notes field
+	SecretToken SecretType = "token" // an API token, kept in the password field
+)
+
+// ParseSecretType checks a secret type name
+func ParseSecretType(value string) (SecretType, error) {
+	switch secretType := SecretType(value); secretType {
+	case SecretLogin, SecretNote, SecretToken:
+		return secretType, nil
+	}
+	return "", fmt.Errorf("invalid secret type: %s (use login, note or token)", value)
+}
+
+// SecretMetadata describes a structured secret entry. Its fields are stored
+// separately, as an encrypted object, so listing entries never decrypts
+// their values.
+type SecretMetadata struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Type      SecretType `json:"type"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ObjectID  string     `json:"object_id"`
+	// FieldNames lists the fields set, without their values
+	FieldNames []string `json:"field_names,omitempty"`
+}
+
+// SecretFields holds the values of a secret entry's fields
+type SecretFields struct {
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Notes    string            `json:"notes,omitempty"`
+	Custom   map[string]string `json:"custom,omitempty"`
+}
+
+// standardSecretFields are the named fields of every secret entry
+var standardSecretFields = []string{"username", "password", "url", "notes"}
+
+// ValidateSecretField checks a field name: letters, digits, '_' and '-'
+// (no '.', which separates an entry from a field in templates)
+func ValidateSecretField(name string) error {
+	if name == "" {
+		return errors.New("empty field name")
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return fmt.Errorf("invalid field name %q (use letters, digits, '_' and '-')", name)
+		}
+	}
+	return nil
+}
+
+// field returns a pointer to a standard field, or nil for a custom one
+func (f *SecretFields) field(name string) *string {
+	switch name {
+	case "username":
+		return &f.Username
+	case "password":
+		return &f.Password
+	case "url":
+		return &f.URL
+	case "notes":
+		return &f.Notes
+	}
+	return nil
+}
+
+// Get returns the value of a standard or custom field
+func (f *SecretFields) Get(name string) (string, bool) {
+	if p := f.field(name); p != nil {
+		return *p, *p != ""
+	}
+	value, ok := f.Custom[name]
+	return value, ok
+}
+
+// Set sets a standard or custom field; an empty value removes it
+func (f *SecretFields) Set(name, value string) {
+	if p := f.field(name); p != nil {
+		*p = value
+		return
+	}
+	if value == "" {
+		delete(f.Custom, name)
+		return
+	}
+	if f.Custom == nil {
+		f.Custom = make(map[string]string)
+	}
+	f.Custom[name] = value
+}
+
+// Names returns the names of the fields set: standard fields first, then
+// custom fields sorted
+func (f *SecretFields) Names() []string {
+	var names []string
+	for _, name := range standardSecretFields {
+		if value, _ := f.Get(name); value != "" {
+			names = append(names, name)
+		}
+	}
+	custom := make([]string, 0, len(f.Custom))
+	for name := range f.Custom {
+		custom = append(custom, name)
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
 }
 
 // VaultMetadata stores the list of all files in the vault
 type VaultMetadata struct {
-	Version int            `json:"version"`
-	Files   []FileMetadata `json:"files"`
+	Version int                      `json:"version"`
+	Files   []FileMetadata           `json:"files"`
+	Chunks  map[string]ChunkMetadata `json:"chunks,omitempty"`
+	Secrets []SecretMetadata         `json:"secrets,omitempty"`
 }
 
 // GetVaultPaths returns the standard paths for a vault
@@ -62,16 +250,20 @@ func GetVaultPaths(rootPath string) VaultPaths {
 		Root:        rootPath,
 		VaultDir:    vaultDir,
 		Meta:        filepath.Join(vaultDir, metaFileName),
+		MetaLog:     filepath.Join(vaultDir, metaLogFileName),
 		Salt:        filepath.Join(vaultDir, saltFileName),
-		Config:      filepath.Join(vaultDir, configFileName),
 		Objects:     filepath.Join(vaultDir, objectsDirName),
 		MasterKey:   filepath.Join(vaultDir, masterKeyFileName),
 		RecoveryKey: filepath.Join(vaultDir, recoveryKeyFileName),
 	}
 }
 
-// VaultExists checks if a vault exists at the given path
+// VaultExists checks if a vault (directory or container file) exists at the given path
 func VaultExists(rootPath string) bool {
+	if IsContainer(rootPath) {
+		return containerExists(rootPath)
+	}
+
 	paths := GetVaultPaths(rootPath)
 	info, err := os.Stat(paths.VaultDir)
 	if err != nil {
@@ -80,8 +272,12 @@ func VaultExists(rootPath string) bool {
 	return info.IsDir()
 }
 
-// InitializeVault creates the vault directory structure
+// InitializeVault creates the vault directory structure, or an empty container file
 func InitializeVault(rootPath string) error {
+	if IsContainer(rootPath) {
+		return createContainer(rootPath)
+	}
+
 	paths := GetVaultPaths(rootPath)
 
 	if VaultExists(rootPath) {
@@ -104,32 +300,159 @@ func InitializeVault(rootPath string) error {
 	return nil
 }
 
-// ListDirectoryFiles returns a list of regular files in the directory (excluding hidden files and directories)
-func ListDirectoryFiles(dirPath string) ([]string, error) {
-	entries, err := os.ReadDir(dirPath)
+// RemoveVault deletes a vault's storage (the .vaultix directory or container file)
+// Plaintext files next to the vault are left alone
+func RemoveVault(rootPath string) error {
+	if IsContainer(rootPath) {
+		containersMu.Lock()
+		delete(containers, rootPath)
+		containersMu.Unlock()
+		return os.Remove(rootPath)
+	}
+	return os.RemoveAll(GetVaultPaths(rootPath).VaultDir)
+}
+
+// CopyVaultFiles copies the salt, key envelopes and metadata from one
+// vault to another, which may use a different layout. Objects are not copied.
+func CopyVaultFiles(srcRoot, dstRoot string) error {
+	for _, name := range []string{saltFileName, masterKeyFileName, recoveryKeyFileName, metaFileName, metaLogFileName} {
+		data, err := readVaultFile(srcRoot, name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := writeVaultFile(dstRoot, name, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ListDirectoryTree returns the regular files and symbolic links under the
+// directory, recursively, that the filter selects (a nil filter selects all).
+// Hidden files and directories (starting with .) are skipped unless the
+// filter includes them, as are paths matched by the .vaultixignore at the top
+// of the tree. Directories holding a vault and container vault files are
+// always skipped. Symbolic links to directories are listed as links, not
+// followed.
+func ListDirectoryTree(dirPath string, filter *FileFilter) ([]string, error) {
+	tf, err := newTreeFilter(dirPath, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
+		return nil, err
 	}
 
 	var files []string
-	for _, entry := range entries {
-		// Skip directories and hidden files (starting with .)
-		if entry.IsDir() || entry.Name()[0] == '.' {
-			continue
+	err = filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dirPath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if tf.skip(rel, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			// Never descend into another vault
+			if VaultExists(path) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		// Get full path
-		fullPath := filepath.Join(dirPath, entry.Name())
-		files = append(files, fullPath)
+		if !entry.Type().IsRegular() && entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		if entry.Type().IsRegular() && containerExists(path) {
+			return nil
+		}
+		if !tf.included(rel) {
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	return files, nil
 }
 
+// RemoveEmptyParents removes the directories holding the given files, and
+// their parents up to (but not including) rootDir, where they are now empty
+func RemoveEmptyParents(files []string, rootDir string) {
+	for _, dir := range parentDirs(files, rootDir) {
+		// Fails harmlessly if the directory still holds other files
+		os.Remove(dir)
+	}
+}
+
+// EmptiedDirectories returns the directories RemoveEmptyParents would remove
+// once the given files are deleted, deepest first
+func EmptiedDirectories(files []string, rootDir string) []string {
+	removed := make(map[string]bool, len(files))
+	for _, file := range files {
+		removed[file] = true
+	}
+
+	var emptied []string
+	for _, dir := range parentDirs(files, rootDir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		empty := true
+		for _, entry := range entries {
+			if !removed[filepath.Join(dir, entry.Name())] {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			removed[dir] = true
+			emptied = append(emptied, dir)
+		}
+	}
+	return emptied
+}
+
+// parentDirs returns the directories holding the given files and their parents
+// below rootDir, deepest first
+func parentDirs(files []string, rootDir string) []string {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		for dir := filepath.Dir(file); dir != rootDir && strings.HasPrefix(dir, rootDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	// Deepest first, so parents are empty by the time they are tried
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return sorted
+}
+
 // WriteSalt stores the salt for the vault
 func WriteSalt(rootPath string, salt []byte) error {
-	paths := GetVaultPaths(rootPath)
-	if err := os.WriteFile(paths.Salt, salt, 0600); err != nil {
+	if err := writeVaultFile(rootPath, saltFileName, salt); err != nil {
 		return fmt.Errorf("failed to write salt: %w", err)
 	}
 	return nil
@@ -137,8 +460,7 @@ func WriteSalt(rootPath string, salt []byte) error {
 
 // ReadSalt reads the salt from the vault
 func ReadSalt(rootPath string) ([]byte, error) {
-	paths := GetVaultPaths(rootPath)
-	salt, err := os.ReadFile(paths.Salt)
+	salt, err := readVaultFile(rootPath, saltFileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrVaultNotFound
@@ -150,8 +472,7 @@ func ReadSalt(rootPath string) ([]byte, error) {
 
 // WriteMasterKey stores the encrypted master key
 func WriteMasterKey(rootPath string, encryptedMasterKey []byte) error {
-	paths := GetVaultPaths(rootPath)
-	if err := os.WriteFile(paths.MasterKey, encryptedMasterKey, 0600); err != nil {
+	if err := writeVaultFile(rootPath, masterKeyFileName, encryptedMasterKey); err != nil {
 		return fmt.Errorf("failed to write master key: %w", err)
 	}
 	return nil
@@ -159,8 +480,7 @@ func WriteMasterKey(rootPath string, encryptedMasterKey []byte) error {
 
 // ReadMasterKey reads the encrypted master key
 func ReadMasterKey(rootPath string) ([]byte, error) {
-	paths := GetVaultPaths(rootPath)
-	encryptedMasterKey, err := os.ReadFile(paths.MasterKey)
+	encryptedMasterKey, err := readVaultFile(rootPath, masterKeyFileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrVaultNotFound
@@ -172,8 +492,7 @@ func ReadMasterKey(rootPath string) ([]byte, error) {
 
 // WriteRecoveryKey stores the encrypted master key (encrypted with recovery key)
 func WriteRecoveryKey(rootPath string, encryptedMasterKeyForRecovery []byte) error {
-	paths := GetVaultPaths(rootPath)
-	if err := os.WriteFile(paths.RecoveryKey, encryptedMasterKeyForRecovery, 0600); err != nil {
+	if err := writeVaultFile(rootPath, recoveryKeyFileName, encryptedMasterKeyForRecovery); err != nil {
 		return fmt.Errorf("failed to write recovery key file: %w", err)
 	}
 	return nil
@@ -181,8 +500,7 @@ func WriteRecoveryKey(rootPath string, encryptedMasterKeyForRecovery []byte) err
 
 // ReadRecoveryKey reads the encrypted master key (for recovery key unlock)
 func ReadRecoveryKey(rootPath string) ([]byte, error) {
-	paths := GetVaultPaths(rootPath)
-	encryptedMasterKeyForRecovery, err := os.ReadFile(paths.RecoveryKey)
+	encryptedMasterKeyForRecovery, err := readVaultFile(rootPath, recoveryKeyFileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrVaultNotFound
@@ -194,8 +512,7 @@ func ReadRecoveryKey(rootPath string) ([]byte, error) {
 
 // ReadMetadata reads and returns the encrypted metadata
 func ReadMetadata(rootPath string) ([]byte, error) {
-	paths := GetVaultPaths(rootPath)
-	data, err := os.ReadFile(paths.Meta)
+	data, err := readVaultFile(rootPath, metaFileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrVaultNotFound
@@ -207,19 +524,40 @@ func ReadMetadata(rootPath string) ([]byte, error) {
 
 // WriteMetadata writes encrypted metadata to disk
 func WriteMetadata(rootPath string, data []byte) error {
-	paths := GetVaultPaths(rootPath)
-	if err := os.WriteFile(paths.Meta, data, 0600); err != nil {
+	if err := writeVaultFile(rootPath, metaFileName, data); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
 	return nil
 }
 
+// readVaultFile reads a named file from the vault directory or container
+func readVaultFile(rootPath, name string) ([]byte, error) {
+	if IsContainer(rootPath) {
+		return readContainerEntry(rootPath, name)
+	}
+	return os.ReadFile(filepath.Join(GetVaultPaths(rootPath).VaultDir, name))
+}
+
+// writeVaultFile writes a named file to the vault directory or container
+func writeVaultFile(rootPath, name string, data []byte) error {
+	if IsContainer(rootPath) {
+		return writeContainerEntry(rootPath, name, data)
+	}
+	return os.WriteFile(filepath.Join(GetVaultPaths(rootPath).VaultDir, name), data, 0600)
+}
+
 // GenerateObjectID creates a unique ID for an object based on name and timestamp
 func GenerateObjectID(originalName string) string {
 	hash := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", originalName, time.Now().UnixNano())))
 	return hex.EncodeToString(hash[:8])
 }
 
+// ContentHash returns the hex SHA-256 digest of a file's plaintext
+func ContentHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
 // GetObjectPath returns the path for an encrypted object
 func GetObjectPath(rootPath, objectID string) string {
 	paths := GetVaultPaths(rootPath)
@@ -227,7 +565,16 @@ func GetObjectPath(rootPath, objectID string) string {
 }
 
 // WriteObject writes encrypted data to an object file
-func WriteObject(rootPath, objectID string, data []byte) error {
+// Small objects go into a pack file when packs is not nil; they can be read
+// back at once, but packs must be flushed before they are referenced.
+func WriteObject(rootPath string, packs *Packs, objectID string, data []byte) error {
+	if IsContainer(rootPath) {
+		return writeContainerEntry(rootPath, containerObjectPrefix+objectID, data)
+	}
+	if packs != nil && len(data) <= packObjectMaxSize {
+		return packs.writePacked(objectID, data)
+	}
+
 	objectPath := GetObjectPath(rootPath, objectID)
 	if err := os.WriteFile(objectPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write object: %w", err)
@@ -235,12 +582,24 @@ func WriteObject(rootPath, objectID string, data []byte) error {
 	return nil
 }
 
-// ReadObject reads encrypted data from an object file
-func ReadObject(rootPath, objectID string) ([]byte, error) {
+// ReadObject reads encrypted data from an object file, or from a pack when
+// packs is not nil
+func ReadObject(rootPath string, packs *Packs, objectID string) ([]byte, error) {
+	if IsContainer(rootPath) {
+		data, err := readContainerEntry(rootPath, containerObjectPrefix+objectID)
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotInVault
+		}
+		return data, err
+	}
+
 	objectPath := GetObjectPath(rootPath, objectID)
 	data, err := os.ReadFile(objectPath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if packs != nil {
+				return packs.readPacked(objectID)
+			}
 			return nil, ErrFileNotInVault
 		}
 		return nil, fmt.Errorf("failed to read object: %w", err)
@@ -248,11 +607,23 @@ func ReadObject(rootPath, objectID string) ([]byte, error) {
 	return data, nil
 }
 
-// DeleteObject removes an encrypted object file
-func DeleteObject(rootPath, objectID string) error {
+// DeleteObject removes an encrypted object file or its pack entry; pack
+// entries are removed from the index once packs is flushed
+func DeleteObject(rootPath string, packs *Packs, objectID string) error {
+	if IsContainer(rootPath) {
+		err := deleteContainerEntry(rootPath, containerObjectPrefix+objectID)
+		if os.IsNotExist(err) {
+			return ErrFileNotInVault
+		}
+		return err
+	}
+
 	objectPath := GetObjectPath(rootPath, objectID)
 	if err := os.Remove(objectPath); err != nil {
 		if os.IsNotExist(err) {
+			if packs != nil {
+				return packs.deletePacked(objectID)
+			}
 			return ErrFileNotInVault
 		}
 		return fmt.Errorf("failed to delete object: %w", err)
@@ -261,8 +632,10 @@ func DeleteObject(rootPath, objectID string) error {
 }
 
 // ReadPlaintextFile reads a file from disk (for adding to vault)
+// Symbolic links are not followed; their data is empty and the target is
+// captured by ReadFileAttributes.
 func ReadPlaintextFile(filePath string) ([]byte, os.FileInfo, error) {
-	info, err := os.Stat(filePath)
+	info, err := os.Lstat(filePath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to stat file: %w", err)
 	}
@@ -270,6 +643,12 @@ func ReadPlaintextFile(filePath string) ([]byte, os.FileInfo, error) {
 	if info.IsDir() {
 		return nil, nil, errors.New("cannot add directory, only files are supported")
 	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return []byte{}, info, nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("cannot add %s: not a regular file or symbolic link", filePath)
+	}
 
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -279,64 +658,110 @@ func ReadPlaintextFile(filePath string) ([]byte, os.FileInfo, error) {
 	return data, info, nil
 }
 
-// WritePlaintextFile writes decrypted data to disk
-func WritePlaintextFile(filePath string, data []byte, modTime time.Time) error {
+// WritePlaintextFile writes decrypted data to disk, restoring the file's
+// attributes. Files without recorded attributes are written with mode 0600.
+// The data is written to a new temporary file (created exclusively, so it
+// never opens an existing file or follows a symbolic link) and then renamed
+// over filePath. An existing symbolic link at filePath is replaced, not
+// followed.
+func WritePlaintextFile(filePath string, data []byte, modTime time.Time, attrs FileAttributes, opts RestoreOptions) error {
 	// Ensure parent directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	if attrs.IsSymlink() {
+		return writeSymlink(filePath, attrs, opts)
+	}
+
+	perm := attrs.Mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	if attrs.Mode == 0 {
+		perm = 0600
+	}
+
+	tmpPath, err := tempPath(dir)
+	if err != nil {
+		return err
+	}
+
+	// Create without group/other access; the final mode is set below once
+	// ownership is restored (chown clears setuid and setgid bits)
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	restoreOwnerAndXattrs(tmpPath, attrs, opts)
+
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
 
 	// Restore modification time
-	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+	if err := os.Chtimes(tmpPath, modTime, modTime); err != nil {
 		// Non-fatal - just log and continue
 		fmt.Fprintf(os.Stderr, "warning: failed to restore modification time: %v\n", err)
 	}
 
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
 	return nil
 }
 
-// SecureDelete overwrites a file before deletion (best effort)
-func SecureDelete(filePath string) error {
-	info, err := os.Stat(filePath)
+// writeSymlink recreates a symbolic link, replacing any existing file
+func writeSymlink(linkPath string, attrs FileAttributes, opts RestoreOptions) error {
+	tmpPath, err := tempPath(filepath.Dir(linkPath))
 	if err != nil {
 		return err
 	}
+	if err := os.Symlink(attrs.LinkTarget, tmpPath); err != nil {
+		return fmt.Errorf("failed to create symbolic link: %w", err)
+	}
+
+	restoreOwnerAndXattrs(tmpPath, attrs, opts)
+
+	if err := os.Rename(tmpPath, linkPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", linkPath, err)
+	}
+	return nil
+}
 
-	// Open file for writing
-	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+// tempPath returns an unused name for a temporary file in dir
+func tempPath(dir string) (string, error) {
+	name, err := randomName()
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to create temporary file name: %w", err)
 	}
-	defer file.Close()
+	return filepath.Join(dir, ".vaultix-"+name+".tmp"), nil
+}
 
-	// Overwrite with random data
-	size := info.Size()
-	buf := make([]byte, 4096)
-	for size > 0 {
-		n := int64(len(buf))
-		if n > size {
-			n = size
-		}
-		// Read random data from crypto/rand
-		if _, err := io.ReadFull(rand.Reader, buf[:n]); err != nil {
-			// If random read fails, just use zeros
-			for i := range buf[:n] {
-				buf[i] = 0
-			}
+// restoreOwnerAndXattrs applies ownership and extended attributes (best effort)
+func restoreOwnerAndXattrs(filePath string, attrs FileAttributes, opts RestoreOptions) {
+	// Only root can give files away, so ownership is restored only when
+	// running as root
+	if !opts.NoOwner && attrs.UID != nil && attrs.GID != nil && os.Geteuid() == 0 {
+		if err := os.Lchown(filePath, *attrs.UID, *attrs.GID); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to restore ownership of %s: %v\n", filePath, err)
 		}
-		if _, err := file.Write(buf[:n]); err != nil {
-			return err
-		}
-		size -= n
 	}
 
-	file.Close()
-
-	// Now delete the file
-	return os.Remove(filePath)
+	if !opts.NoXattrs && len(attrs.Xattrs) > 0 {
+		if err := writeXattrs(filePath, attrs.Xattrs); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to restore extended attributes of %s: %v\n", filePath, err)
+		}
+	}
 }
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoMemoryTempDir is returned when no memory-backed directory is available
+// for decrypted files
+var ErrNoMemoryTempDir = errors.New("no memory-backed temporary directory found (tried /dev/shm and $XDG_RUNTIME_DIR)")
+
+// memoryTempDirs returns the candidate memory-backed (tmpfs) directories
+func memoryTempDirs() []string {
+	dirs := []string{"/dev/shm"}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, runtimeDir)
+	}
+	return dirs
+}
+
+// PrivateTempDir creates a new directory, readable only by the current user,
+// for holding decrypted files
+// The directory is created in base or, if base is "", in the first usable
+// memory-backed directory, so that plaintext never reaches persistent storage.
+// The caller removes it, normally with WipeDir.
+func PrivateTempDir(base string) (string, error) {
+	candidates := []string{base}
+	if base == "" {
+		candidates = memoryTempDirs()
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || !info.IsDir() {
+			if base != "" {
+				return "", fmt.Errorf("temporary directory not found: %s", base)
+			}
+			continue
+		}
+
+		// MkdirTemp creates the directory with mode 0700
+		dir, err := os.MkdirTemp(candidate, "vaultix-")
+		if err != nil {
+			if base != "" {
+				return "", fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			continue
+		}
+		// Make sure of it, whatever the umask
+		if err := os.Chmod(dir, 0700); err != nil {
+			os.Remove(dir)
+			return "", fmt.Errorf("failed to secure temporary directory: %w", err)
+		}
+		return dir, nil
+	}
+
+	return "", ErrNoMemoryTempDir
+}
+
+// WipeDir securely deletes every file below dir and then removes it
+// Files are wiped even if something else (an editor's swap or backup files,
+// say) created them. Returns the first error, after wiping all it can.
+func WipeDir(dir string, method WipeMethod) error {
+	var firstErr error
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			if err := SecureDelete(path, method); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil
+	})
+
+	if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
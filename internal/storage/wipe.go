@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WipeMethod selects how SecureDelete destroys a file's contents
+type WipeMethod string
+
+const (
+	// WipeNone deletes without overwriting, for filesystems where
+	// overwriting cannot reach the old data anyway
+	WipeNone WipeMethod = "none"
+	// WipeSinglePass overwrites once with random data
+	WipeSinglePass WipeMethod = "single"
+	// WipeMultiPass overwrites with zeros, ones and then random data
+	WipeMultiPass WipeMethod = "multi"
+)
+
+// DefaultWipeMethod is used unless the vault config says otherwise
+const DefaultWipeMethod = WipeSinglePass
+
+// ParseWipeMethod checks a wipe method name
+func ParseWipeMethod(value string) (WipeMethod, error) {
+	switch method := WipeMethod(value); method {
+	case WipeNone, WipeSinglePass, WipeMultiPass:
+		return method, nil
+	}
+	return "", fmt.Errorf("invalid wipe method: %s (use none, single or multi)", value)
+}
+
+// wipePasses returns the fill byte of each overwrite pass; -1 means random data
+func (m WipeMethod) wipePasses() []int {
+	switch m {
+	case WipeNone:
+		return nil
+	case WipeMultiPass:
+		return []int{0x00, 0xFF, -1}
+	default:
+		return []int{-1}
+	}
+}
+
+// SecureDelete overwrites a file before deletion (best effort)
+// Each pass is flushed to disk before the next. The file is then truncated and
+// renamed to a random name before it is unlinked, so neither its size nor its
+// name is left behind in the directory. Overwriting cannot reach old data on
+// copy-on-write or flash storage; see WipeWarning.
+func SecureDelete(filePath string, method WipeMethod) error {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return err
+	}
+
+	// A symbolic link holds no file data; overwriting through it would
+	// destroy its target
+	if !info.Mode().IsRegular() {
+		return os.Remove(filePath)
+	}
+
+	// The path may be replaced between the check and the open, so only a
+	// regular file reached without following a link is overwritten
+	file, err := os.OpenFile(filePath, os.O_WRONLY|noFollowFlags, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err = file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("refusing to overwrite %s: not a regular file", filePath)
+	}
+
+	for _, fill := range method.wipePasses() {
+		if err := overwrite(file, info.Size(), fill); err != nil {
+			return err
+		}
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	file.Close()
+
+	// Unlink under a random name, so the original name is not left behind
+	target := filePath
+	if name, err := randomName(); err == nil {
+		renamed := filepath.Join(filepath.Dir(filePath), name)
+		if err := os.Rename(filePath, renamed); err == nil {
+			target = renamed
+		}
+	}
+	if err := os.Remove(target); err != nil {
+		return err
+	}
+
+	syncDir(filepath.Dir(filePath))
+	return nil
+}
+
+// overwrite fills the first size bytes of a file and flushes them to disk
+func overwrite(file *os.File, size int64, fill int) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	if fill >= 0 {
+		for i := range buf {
+			buf[i] = byte(fill)
+		}
+	}
+
+	for size > 0 {
+		n := int64(len(buf))
+		if n > size {
+			n = size
+		}
+		if fill < 0 {
+			// Read random data from crypto/rand
+			if _, err := io.ReadFull(rand.Reader, buf[:n]); err != nil {
+				return fmt.Errorf("failed to generate random data: %w", err)
+			}
+		}
+		if _, err := file.Write(buf[:n]); err != nil {
+			return err
+		}
+		size -= n
+	}
+
+	return file.Sync()
+}
+
+// randomName returns a random file name
+func randomName() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// syncDir flushes a directory's entries to disk (best effort; not every
+// platform can sync a directory)
+func syncDir(dirPath string) {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return
+	}
+	dir.Sync()
+	dir.Close()
+}
+
+// WipeWarning explains why overwriting a file at the given path may leave its
+// old contents recoverable, or returns "" when nothing is known against it
+func WipeWarning(filePath string) string {
+	return filesystemWipeWarning(filePath)
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Filesystems on which overwriting a file does not overwrite its old blocks
+var wipeIneffectiveFilesystems = map[string]string{
+	"apfs":  "APFS (copy-on-write)",
+	"zfs":   "ZFS (copy-on-write)",
+	"nfs":   "NFS (the server controls the storage)",
+	"smbfs": "SMB (the server controls the storage)",
+}
+
+// filesystemWipeWarning checks the filesystem type with statfs
+func filesystemWipeWarning(filePath string) string {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(filePath, &fs); err != nil {
+		return ""
+	}
+
+	name := unix.ByteSliceToString(fs.Fstypename[:])
+	if reason, ok := wipeIneffectiveFilesystems[name]; ok {
+		return "the file is on " + reason
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Filesystems on which overwriting a file does not overwrite its old blocks,
+// by statfs magic number
+var wipeIneffectiveFilesystems = map[int64]string{
+	0x9123683E: "btrfs (copy-on-write)",
+	0x2FC12FC1: "ZFS (copy-on-write)",
+	0xF2F52010: "F2FS (log-structured)",
+	0x3434:     "NILFS (log-structured)",
+	0x01021994: "tmpfs (memory may be swapped or reused)",
+	0x858458F6: "ramfs (memory may be reused)",
+	0x794C7630: "overlayfs (the lower layer keeps a copy)",
+	0x6969:     "NFS (the server controls the storage)",
+	0xFF534D42: "CIFS/SMB (the server controls the storage)",
+}
+
+// filesystemWipeWarning checks the filesystem type with statfs, and whether
+// the backing block device is non-rotational (SSD or other flash storage)
+func filesystemWipeWarning(filePath string) string {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(filePath, &fs); err == nil {
+		if reason, ok := wipeIneffectiveFilesystems[int64(fs.Type)]; ok {
+			return "the file is on " + reason
+		}
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(filePath, &st); err != nil {
+		return ""
+	}
+	if isSolidState(unix.Major(uint64(st.Dev)), unix.Minor(uint64(st.Dev))) {
+		return "the file is on an SSD or flash storage (wear levelling keeps old blocks)"
+	}
+	return ""
+}
+
+// isSolidState reports whether sysfs marks a block device as non-rotational
+// A partition's queue settings live on its parent device.
+func isSolidState(major, minor uint32) bool {
+	device, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return false
+	}
+	for _, queue := range []string{"queue/rotational", "../queue/rotational"} {
+		data, err := os.ReadFile(filepath.Join(device, queue))
+		if err == nil {
+			return strings.TrimSpace(string(data)) == "0"
+		}
+	}
+	return false
+}
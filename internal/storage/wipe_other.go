@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package storage
+
+// filesystemWipeWarning reports nothing; the filesystem type is not checked
+// on this platform
+func filesystemWipeWarning(filePath string) string {
+	return ""
+}
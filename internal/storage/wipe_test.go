@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecureDelete(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("keep me"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "file")
+	for _, method := range []WipeMethod{WipeNone, WipeSinglePass, WipeMultiPass} {
+		if err := os.WriteFile(file, []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := SecureDelete(file, method); err != nil {
+			t.Fatalf("SecureDelete %s: %v", method, err)
+		}
+	}
+
+	// Deleting a link removes the link, never its target's contents
+	if err := SecureDelete(link, WipeSinglePass); err != nil {
+		t.Fatalf("SecureDelete link: %v", err)
+	}
+	if got, err := os.ReadFile(target); err != nil || string(got) != "keep me" {
+		t.Errorf("link target = %q, %v; want it unchanged", got, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "target" {
+		t.Errorf("directory holds %v, want only the link target", entries)
+	}
+}
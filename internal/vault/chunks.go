@@ -0,0 +1,206 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/chunker"
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+const (
+	// Subkey purposes derived from the master key
+	chunkIDPurpose   = "chunk-id"
+	chunkSeedPurpose = "chunker"
+)
+
+// Stats summarizes vault contents and deduplication savings
+// LogicalSize and StoredSize both cover the current files only, so their
+// ratio is the deduplication of those files; chunks kept only for earlier
+// versions, snapshots and the trash are counted in HistorySize.
+type Stats struct {
+	Files       int   // number of files in the vault
+	Chunks      int   // number of unique stored chunks, including history
+	LogicalSize int64 // total size of the current files
+	StoredSize  int64 // plaintext bytes of the unique chunks of the current files
+	HistorySize int64 // plaintext bytes of chunks kept only for history
+}
+
+// DedupRatio returns logical size divided by stored size (1.0 means no savings)
+func (s *Stats) DedupRatio() float64 {
+	if s.StoredSize == 0 {
+		return 1.0
+	}
+	return float64(s.LogicalSize) / float64(s.StoredSize)
+}
+
+// Stats returns size and deduplication statistics for the vault
+func (s *Session) Stats() (*Stats, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Files:  len(ms.files),
+		Chunks: len(ms.chunks),
+	}
+	current := make(map[string]bool)
+	for _, f := range ms.files {
+		stats.LogicalSize += f.Size
+		for _, chunkID := range f.Chunks {
+			current[chunkID] = true
+		}
+	}
+	for chunkID, c := range ms.chunks {
+		if current[chunkID] {
+			stats.StoredSize += c.Size
+		} else {
+			stats.HistorySize += c.Size
+		}
+	}
+
+	return stats, nil
+}
+
+// storeFileData splits data into content-defined chunks, writes the chunks not
+// yet in the vault and stages a reference on each one in the metadata
+// Returns the chunk IDs in order and the IDs of chunks newly written
+func (v *Vault) storeFileData(ms *metaStore, data []byte) ([]string, []string, error) {
+	masterKey := ms.key
+
+	idKey, err := crypto.DeriveSubkey(masterKey, chunkIDPurpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(idKey)
+	seed, err := crypto.DeriveSubkey(masterKey, chunkSeedPurpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(seed)
+
+	pieces := chunker.New(seed).Split(data)
+	if len(pieces) == 0 {
+		// Empty files still get one (empty) chunk so that every chunked
+		// file has a non-empty chunk list
+		pieces = [][]byte{{}}
+	}
+
+	chunkIDs := make([]string, 0, len(pieces))
+	var written []string
+	for _, piece := range pieces {
+		chunkID := crypto.ComputeChunkID(idKey, piece)
+
+		chunk, exists := ms.chunks[chunkID]
+		if !exists {
+			encryptedData, err := crypto.Encrypt(piece, masterKey)
+			if err != nil {
+				v.deleteObjects(written)
+				return nil, nil, fmt.Errorf("failed to encrypt chunk: %w", err)
+			}
+			if err := storage.WriteObject(v.rootPath, v.packs, chunkID, encryptedData); err != nil {
+				v.deleteObjects(written)
+				return nil, nil, err
+			}
+			written = append(written, chunkID)
+			chunk.Size = int64(len(piece))
+		}
+
+		chunk.RefCount++
+		ms.putChunk(chunkID, chunk)
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+
+	return chunkIDs, written, nil
+}
+
+// loadFileData reads and decrypts a file's contents from the vault, checking
+// them against the stored hash
+func (v *Vault) loadFileData(masterKey []byte, fileMeta *storage.FileMetadata) ([]byte, error) {
+	data := make([]byte, 0, fileMeta.Size)
+	for _, chunkID := range fileMeta.Chunks {
+		encryptedData, err := storage.ReadObject(v.rootPath, v.packs, chunkID)
+		if err != nil {
+			return nil, err
+		}
+		piece, err := crypto.Decrypt(encryptedData, masterKey)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, piece...)
+	}
+
+	// Files added before hashes were recorded cannot be checked
+	if fileMeta.SHA256 != "" && storage.ContentHash(data) != fileMeta.SHA256 {
+		return nil, ErrHashMismatch
+	}
+
+	return data, nil
+}
+
+// retainFile stages taking an extra reference on each chunk of every
+// version of the file
+func (ms *metaStore) retainFile(fileMeta *storage.FileMetadata) {
+	for _, chunkIDs := range fileChunkLists(fileMeta) {
+		for _, chunkID := range chunkIDs {
+			chunk := ms.chunks[chunkID]
+			chunk.RefCount++
+			ms.putChunk(chunkID, chunk)
+		}
+	}
+}
+
+// releaseFile stages dropping the chunk references of every version of the
+// file from the metadata
+// Returns the objects no longer referenced by any file or snapshot, to be
+// deleted once the metadata change is committed
+func (ms *metaStore) releaseFile(fileMeta *storage.FileMetadata) []string {
+	var unreferenced []string
+	for _, chunkIDs := range fileChunkLists(fileMeta) {
+		unreferenced = append(unreferenced, ms.releaseChunks(chunkIDs)...)
+	}
+	return unreferenced
+}
+
+// releaseChunks stages dropping one reference on each chunk
+// Returns the chunks no longer referenced
+func (ms *metaStore) releaseChunks(chunkIDs []string) []string {
+	var unreferenced []string
+	for _, chunkID := range chunkIDs {
+		chunk, exists := ms.chunks[chunkID]
+		if !exists {
+			continue
+		}
+
+		chunk.RefCount--
+		if chunk.RefCount > 0 {
+			ms.putChunk(chunkID, chunk)
+			continue
+		}
+
+		ms.deleteChunk(chunkID)
+		unreferenced = append(unreferenced, chunkID)
+	}
+
+	return unreferenced
+}
+
+// deleteObjects removes objects (best effort, used for cleanup)
+func (v *Vault) deleteObjects(objectIDs []string) {
+	for _, objectID := range objectIDs {
+		if err := storage.DeleteObject(v.rootPath, v.packs, objectID); err != nil && err != storage.ErrFileNotInVault {
+			fmt.Fprintf(os.Stderr, "warning: failed to delete object %s: %v\n", objectID, err)
+		}
+	}
+	if err := v.flushPacks(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save pack index: %v\n", err)
+	}
+}
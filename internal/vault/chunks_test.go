@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// testData returns n bytes that are the same for the same seed
+func testData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// addData stores data under name, failing the test on error
+func addData(t *testing.T, session *Session, name string, data []byte) {
+	t.Helper()
+	if _, err := session.AddReader(name, bytes.NewReader(data)); err != nil {
+		t.Fatalf("AddReader %s: %v", name, err)
+	}
+}
+
+// refCounts returns the reference count of each chunk in the metadata
+func refCounts(t *testing.T, session *Session) map[string]int {
+	t.Helper()
+	ms, err := session.vault.openMeta(session.masterKey)
+	if err != nil {
+		t.Fatalf("openMeta: %v", err)
+	}
+	counts := make(map[string]int)
+	for chunkID, chunk := range ms.chunks {
+		counts[chunkID] = chunk.RefCount
+	}
+	return counts
+}
+
+// objectExists reports whether an object is stored, loose or packed
+func objectExists(t *testing.T, session *Session, objectID string) bool {
+	t.Helper()
+	_, err := storage.ReadObject(session.vault.rootPath, session.vault.packs, objectID)
+	return err == nil
+}
+
+func TestChunksDeduplicated(t *testing.T) {
+	session := newTestSession(t, "directory")
+	data := testData(1, 3*1024*1024)
+	addData(t, session, "a.bin", data)
+	single := refCounts(t, session)
+	if len(single) < 2 {
+		t.Fatalf("3 MB stored as %d chunks, want several", len(single))
+	}
+
+	// The same contents under another name only add references
+	addData(t, session, "b.bin", data)
+	counts := refCounts(t, session)
+	if len(counts) != len(single) {
+		t.Errorf("second copy added chunks: %d, want %d", len(counts), len(single))
+	}
+	for chunkID, n := range counts {
+		if n != 2 {
+			t.Errorf("chunk %s has %d references, want 2", chunkID[:8], n)
+		}
+	}
+
+	// Removing one copy keeps the data of the other
+	if err := session.RemoveFile("a.bin", true); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	for chunkID, n := range refCounts(t, session) {
+		if n != 1 {
+			t.Errorf("chunk %s has %d references after removing a copy, want 1", chunkID[:8], n)
+		}
+		if !objectExists(t, session, chunkID) {
+			t.Errorf("chunk %s deleted while still referenced", chunkID[:8])
+		}
+	}
+
+	// Removing the last copy deletes the chunks
+	if err := session.RemoveFile("b.bin", true); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	if counts := refCounts(t, session); len(counts) != 0 {
+		t.Errorf("%d chunks left after removing every file", len(counts))
+	}
+	for chunkID := range single {
+		if objectExists(t, session, chunkID) {
+			t.Errorf("chunk %s still stored after its last reference went", chunkID[:8])
+		}
+	}
+}
+
+func TestChunksReleasedByPrune(t *testing.T) {
+	session := newTestSession(t, "directory")
+	addData(t, session, "a.bin", testData(1, 100))
+	old := refCounts(t, session)
+	addData(t, session, "a.bin", testData(2, 100))
+
+	if len(refCounts(t, session)) != 2 {
+		t.Fatal("two versions do not hold two chunks")
+	}
+
+	if _, err := session.Prune(PruneOptions{KeepLast: 1}); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	counts := refCounts(t, session)
+	if len(counts) != 1 {
+		t.Errorf("%d chunks after pruning to one version, want 1", len(counts))
+	}
+	for chunkID := range old {
+		if _, exists := counts[chunkID]; exists || objectExists(t, session, chunkID) {
+			t.Errorf("chunk %s of the pruned version is still stored", chunkID[:8])
+		}
+	}
+}
+
+func TestChunksReleasedByEmptyTrash(t *testing.T) {
+	session := newTestSession(t, "directory")
+	addData(t, session, "a.bin", testData(1, 100))
+	stored := refCounts(t, session)
+
+	// The trash keeps the file's references until it is emptied
+	if err := session.RemoveFile("a.bin", false); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	for chunkID := range stored {
+		if refCounts(t, session)[chunkID] != 1 || !objectExists(t, session, chunkID) {
+			t.Errorf("chunk %s released while the file is in the trash", chunkID[:8])
+		}
+	}
+
+	if _, err := session.EmptyTrash(); err != nil {
+		t.Fatalf("EmptyTrash: %v", err)
+	}
+	if counts := refCounts(t, session); len(counts) != 0 {
+		t.Errorf("%d chunks left after emptying the trash", len(counts))
+	}
+	for chunkID := range stored {
+		if objectExists(t, session, chunkID) {
+			t.Errorf("chunk %s still stored after emptying the trash", chunkID[:8])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// Config returns the vault's settings, with defaults for those not set
+func (s *Session) Config() (*storage.VaultConfig, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.vaultConfig(), nil
+}
+
+// SetConfig checks and stores the vault's settings
+// Unset values keep using their defaults.
+func (s *Session) SetConfig(config *storage.VaultConfig) error {
+	if config.TrashRetention != "" {
+		if _, err := ParseRetention(config.TrashRetention); err != nil {
+			return err
+		}
+	}
+	if config.Wipe != "" {
+		if _, err := storage.ParseWipeMethod(config.Wipe); err != nil {
+			return err
+		}
+	}
+
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return err
+	}
+
+	ms.putConfig(*config)
+	if err := ms.commit(); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	return nil
+}
+
+// vaultConfig returns a copy of the settings, with defaults for those not set
+func (ms *metaStore) vaultConfig() *storage.VaultConfig {
+	config := storage.DefaultConfig()
+	if ms.config != nil {
+		*config = *ms.config
+		config.ApplyDefaults()
+	}
+	return config
+}
@@ -0,0 +1,128 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// ConflictPolicy selects what extraction does when an output file already exists
+type ConflictPolicy string
+
+const (
+	ConflictAsk       ConflictPolicy = "ask"       // decide per file through ExtractOptions.Ask
+	ConflictSkip      ConflictPolicy = "skip"      // keep the existing file
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace the existing file
+	ConflictRename    ConflictPolicy = "rename"    // write beside it as "name (1).ext"
+	ConflictNewer     ConflictPolicy = "newer"     // replace it only if the stored file is newer
+)
+
+// ParseConflictPolicy checks a conflict policy name
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case ConflictAsk, ConflictSkip, ConflictOverwrite, ConflictRename, ConflictNewer:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid conflict policy: %s (use ask, skip, overwrite, rename or newer)", value)
+}
+
+// ExtractResult summarizes an extraction
+type ExtractResult struct {
+	Extracted []string      // names of the files written
+	Skipped   []string      // names of files not written because the output existed
+	Renamed   []RenamedFile // files written to another path because the output existed
+}
+
+// RenamedFile is a file written under a new path to avoid overwriting another
+type RenamedFile struct {
+	Name string // name in the vault
+	Path string // path written
+}
+
+// resolveConflict returns the path to write a file to, applying the conflict
+// policy when outputPath already exists
+// Returns "" if the file should be skipped.
+func resolveConflict(outputPath string, fileMeta *storage.FileMetadata, opts ExtractOptions) (string, error) {
+	existing, err := os.Lstat(outputPath)
+	if os.IsNotExist(err) {
+		return outputPath, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s: %w", outputPath, err)
+	}
+
+	policy := opts.OnConflict
+	if policy == ConflictAsk {
+		policy = ConflictSkip
+		if opts.Ask != nil {
+			policy = opts.Ask(outputPath, fileMeta, existing)
+		}
+	}
+
+	switch policy {
+	case ConflictSkip, ConflictAsk:
+		return "", nil
+	case ConflictNewer:
+		if !fileMeta.ModTime.After(existing.ModTime()) {
+			return "", nil
+		}
+		return outputPath, nil
+	case ConflictRename:
+		return freePath(outputPath)
+	default:
+		return outputPath, nil
+	}
+}
+
+// freePath returns the first of "name (1).ext", "name (2).ext", ... that does
+// not exist
+func freePath(filePath string) (string, error) {
+	ext := filepath.Ext(filePath)
+	if ext == filepath.Base(filePath) {
+		// A dotfile such as .env has no extension
+		ext = ""
+	}
+	stem := strings.TrimSuffix(filePath, ext)
+
+	for i := 1; i < 10000; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free name found for %s", filePath)
+}
+
+// writeExtracted decrypts a file and writes it to outputPath, applying the
+// conflict policy, and records the outcome in result
+// Reports whether the file was written.
+func (v *Vault) writeExtracted(masterKey []byte, fileMeta *storage.FileMetadata, outputPath string, opts ExtractOptions, result *ExtractResult) (bool, error) {
+	target, err := resolveConflict(outputPath, fileMeta, opts)
+	if err != nil {
+		return false, err
+	}
+	if target == "" {
+		result.Skipped = append(result.Skipped, fileMeta.OriginalName)
+		return false, nil
+	}
+
+	// Read and decrypt file contents
+	plaintext, err := v.loadFileData(masterKey, fileMeta)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt %s: %w", fileMeta.OriginalName, err)
+	}
+
+	// Write decrypted file
+	if err := storage.WritePlaintextFile(target, plaintext, fileMeta.ModTime, fileMeta.FileAttributes, opts.Restore); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", fileMeta.OriginalName, err)
+	}
+
+	result.Extracted = append(result.Extracted, fileMeta.OriginalName)
+	if target != outputPath {
+		result.Renamed = append(result.Renamed, RenamedFile{Name: fileMeta.OriginalName, Path: target})
+	}
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// Convert copies the vault to destPath, which may use the other storage layout
+// (a directory vault or a single-file container). The source is left untouched.
+func (s *Session) Convert(destPath string) error {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	// Read and decrypt metadata to find every object the vault references
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.InitializeVault(destPath); err != nil {
+		return err
+	}
+
+	// The copy reports progress through the session's callback
+	dest := &Vault{rootPath: destPath, onProgress: v.onProgress}
+	defer dest.closePacks()
+	if err := dest.copyFrom(v, ms); err != nil {
+		// Don't leave a half-written vault behind
+		storage.RemoveVault(destPath)
+		return err
+	}
+
+	return nil
+}
+
+// copyFrom copies key envelopes, metadata and all referenced objects from src
+func (v *Vault) copyFrom(src *Vault, ms *metaStore) error {
+	if err := storage.CopyVaultFiles(src.rootPath, v.rootPath); err != nil {
+		return err
+	}
+
+	// Objects are already encrypted, so they are copied as-is
+	if err := v.openPacks(ms.key); err != nil {
+		return err
+	}
+
+	objectIDs := ms.objectIDs()
+	for i, objectID := range objectIDs {
+		if v.onProgress != nil {
+			v.onProgress(i+1, len(objectIDs), objectID)
+		}
+
+		data, err := storage.ReadObject(src.rootPath, src.packs, objectID)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %w", objectID, err)
+		}
+		if err := storage.WriteObject(v.rootPath, v.packs, objectID, data); err != nil {
+			return fmt.Errorf("failed to write object %s: %w", objectID, err)
+		}
+	}
+
+	return v.flushPacks()
+}
+
+// objectIDs returns the IDs of all objects the metadata refers to
+func (ms *metaStore) objectIDs() []string {
+	var objectIDs []string
+	for chunkID := range ms.chunks {
+		objectIDs = append(objectIDs, chunkID)
+	}
+	for _, snap := range ms.snapshots {
+		objectIDs = append(objectIDs, snap.ObjectID)
+	}
+	for _, secret := range ms.secrets {
+		objectIDs = append(objectIDs, secret.ObjectID)
+	}
+	return objectIDs
+}
@@ -0,0 +1,32 @@
+package vault
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+func TestConvertReportsProgress(t *testing.T) {
+	session := newTestSession(t, "directory")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := session.AddReader(name, strings.NewReader("contents of "+name)); err != nil {
+			t.Fatalf("AddReader %s: %v", name, err)
+		}
+	}
+
+	var calls, total int
+	session.SetProgressCallback(func(current, n int, message string) {
+		calls++
+		total = n
+	})
+
+	destPath := filepath.Join(t.TempDir(), "copy"+storage.ContainerExt)
+	if err := session.Convert(destPath); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if calls == 0 || calls != total {
+		t.Errorf("progress reported %d of %d objects", calls, total)
+	}
+}
@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// EditFile decrypts a vaulted file into a private temporary directory, calls
+// edit with the path of the decrypted copy, and stores the result as the next
+// version if its contents changed
+// The directory is created in tempBase, or on a memory-backed filesystem if
+// tempBase is "" (see storage.PrivateTempDir). It is wiped with everything in
+// it before EditFile returns, whether or not edit succeeds. If edit returns an
+// error, nothing is stored. The file's attributes are kept; only its contents,
+// size and modification time change.
+func (s *Session) EditFile(name, tempBase string, edit func(path string) error) (*UpdateResult, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	fileMeta := ms.fileByName(name)
+	if fileMeta == nil {
+		return nil, ErrFileNotFound
+	}
+	if fileMeta.IsSymlink() {
+		return nil, fmt.Errorf("cannot edit %s: %w", name, ErrSymlink)
+	}
+
+	w, err := v.newWiper(ms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt file contents
+	plaintext, err := v.loadFileData(masterKey, fileMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+
+	dir, err := storage.PrivateTempDir(tempBase)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := storage.WipeDir(dir, w.method); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to wipe temporary copy in %s: %v\n", dir, err)
+		}
+	}()
+
+	// Keep the base name, so editors can pick a mode from the extension
+	tempPath := filepath.Join(dir, path.Base(name))
+	if err := writeTempCopy(tempPath, plaintext); err != nil {
+		return nil, err
+	}
+
+	if err := edit(tempPath); err != nil {
+		return nil, err
+	}
+
+	// Read the file back; editors may have replaced it rather than rewritten it
+	info, err := os.Lstat(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("edited file is no longer a regular file")
+	}
+	edited, err := os.ReadFile(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+
+	result := &UpdateResult{Name: fileMeta.OriginalName, Version: currentVersion(fileMeta).Version}
+	if bytes.Equal(edited, plaintext) {
+		return result, nil
+	}
+
+	version, err := v.storeContents(ms, fileMeta.OriginalName, edited, info.ModTime(), fileMeta.FileAttributes)
+	if err != nil {
+		return nil, err
+	}
+	result.Version = version
+	result.Changed = true
+	return result, nil
+}
+
+// writeTempCopy writes decrypted data to a new file readable only by the owner
+func writeTempCopy(filePath string, data []byte) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary copy: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write temporary copy: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary copy: %w", err)
+	}
+	return nil
+}
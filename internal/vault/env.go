@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvVar is an environment variable read from a dotenv file
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// ReadEnv decrypts dotenv files from the vault and returns their variables,
+// merged with MergeEnv
+// Files are found as in ReadFile.
+func (s *Session) ReadEnv(fileNames []string) ([]EnvVar, error) {
+	contents, err := s.ReadFiles(fileNames, ExtractOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lists [][]EnvVar
+	for i, data := range contents {
+		vars, err := ParseDotenv(data)
+		clear(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fileNames[i], err)
+		}
+		lists = append(lists, vars)
+	}
+	return MergeEnv(lists...), nil
+}
+
+// MergeEnv merges lists of variables; a later value for a key replaces an
+// earlier one. Keys keep the order in which they first appear.
+func MergeEnv(lists ...[]EnvVar) []EnvVar {
+	var merged []EnvVar
+	index := make(map[string]int)
+	for _, vars := range lists {
+		for _, ev := range vars {
+			if i, exists := index[ev.Key]; exists {
+				merged[i].Value = ev.Value
+				continue
+			}
+			index[ev.Key] = len(merged)
+			merged = append(merged, ev)
+		}
+	}
+	return merged
+}
+
+// ParseDotenv parses the contents of a dotenv file
+// Each line is KEY=VALUE, optionally preceded by "export". Blank lines and
+// lines starting with # are ignored. Values may be:
+//   - unquoted: surrounding whitespace is trimmed and " #" starts a comment
+//   - 'single-quoted': taken literally, and may span lines
+//   - "double-quoted": \n, \r, \t, \", \\ and \$ are unescaped, and the value
+//     may span lines
+//
+// Variables are not expanded. Errors give the line number but never a value.
+func ParseDotenv(data []byte) ([]EnvVar, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var vars []EnvVar
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if !validEnvKey(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNo, key)
+		}
+		raw := value
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '\'' || value[0] == '"') {
+			quote := value[0]
+			// Join following lines until the closing quote
+			text := value[1:]
+			end := closingQuote(text, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				text += "\n" + lines[i]
+				end = closingQuote(text, quote)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNo)
+			}
+
+			rest := strings.TrimSpace(text[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected text after quoted value", lineNo)
+			}
+
+			value = text[:end]
+			if quote == '"' {
+				value = unescapeDouble(value)
+			}
+		} else {
+			// A # after whitespace starts a comment
+			value = raw
+			for j := 1; j < len(value); j++ {
+				if value[j] == '#' && (value[j-1] == ' ' || value[j-1] == '\t') {
+					value = value[:j]
+					break
+				}
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		vars = append(vars, EnvVar{Key: key, Value: value})
+	}
+
+	return vars, nil
+}
+
+// validEnvKey reports whether a variable name is letters, digits and
+// underscores, not starting with a digit
+func validEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// closingQuote returns the index of the quote ending a value, or -1
+// Inside double quotes, a backslash escapes the next character.
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDouble resolves the escape sequences of a double-quoted value
+func unescapeDouble(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []EnvVar
+	}{
+		{"KEY=value", []EnvVar{{"KEY", "value"}}},
+		{"  KEY = value  ", []EnvVar{{"KEY", "value"}}},
+		{"KEY=", []EnvVar{{"KEY", ""}}},
+		{"KEY= #comment", []EnvVar{{"KEY", ""}}},
+		{"KEY=value # comment", []EnvVar{{"KEY", "value"}}},
+		{"KEY=value\t# comment", []EnvVar{{"KEY", "value"}}},
+		{"KEY=a#b", []EnvVar{{"KEY", "a#b"}}},
+		{"KEY=a=b", []EnvVar{{"KEY", "a=b"}}},
+		{`KEY="a#b"`, []EnvVar{{"KEY", "a#b"}}},
+		{`KEY="a b" # comment`, []EnvVar{{"KEY", "a b"}}},
+		{`KEY='$HOME\n'`, []EnvVar{{"KEY", `$HOME\n`}}},
+		{`KEY='it"s'`, []EnvVar{{"KEY", `it"s`}}},
+		{`KEY="$HOME"`, []EnvVar{{"KEY", "$HOME"}}},
+		{`KEY="a\nb\tc\r\"\\\$"`, []EnvVar{{"KEY", "a\nb\tc\r\"\\$"}}},
+		{`KEY="\x"`, []EnvVar{{"KEY", `\x`}}},
+		{"KEY=\"line1\nline2\"", []EnvVar{{"KEY", "line1\nline2"}}},
+		{"KEY='line1\n# not a comment\nline3'", []EnvVar{{"KEY", "line1\n# not a comment\nline3"}}},
+		{"export KEY=value", []EnvVar{{"KEY", "value"}}},
+		{"export\tKEY=value", []EnvVar{{"KEY", "value"}}},
+		{"exportKEY=value", []EnvVar{{"exportKEY", "value"}}},
+		{"export=value", []EnvVar{{"export", "value"}}},
+		{"# comment\n\nA=1\r\nB=2\n", []EnvVar{{"A", "1"}, {"B", "2"}}},
+		{"A=1\nA=2", []EnvVar{{"A", "1"}, {"A", "2"}}},
+		{"_under_9=x", []EnvVar{{"_under_9", "x"}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseDotenv([]byte(tt.input))
+		if err != nil {
+			t.Errorf("ParseDotenv(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDotenv(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // expected in the error
+	}{
+		{"KEY", "line 1: expected KEY=VALUE"},
+		{"A=1\nsecret-value", "line 2: expected KEY=VALUE"},
+		{"=secret", "line 1: invalid variable name"},
+		{"9KEY=secret", "line 1: invalid variable name"},
+		{"MY-KEY=secret", "line 1: invalid variable name"},
+		{`KEY="secret`, "line 1: unterminated quoted value"},
+		{"A=1\nKEY='secret\nmore", "line 2: unterminated quoted value"},
+		{`KEY="secret\"`, "line 1: unterminated quoted value"},
+		{`KEY="secret" trailing`, "line 1: unexpected text after quoted value"},
+		{`KEY='secret'x`, "line 1: unexpected text after quoted value"},
+	}
+	for _, tt := range tests {
+		_, err := ParseDotenv([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseDotenv(%q) = %v, want %q", tt.input, err, tt.want)
+			continue
+		}
+		// Values may be secrets and never appear in errors
+		if strings.Contains(err.Error(), "secret") {
+			t.Errorf("ParseDotenv(%q) error shows the value: %v", tt.input, err)
+		}
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	got := MergeEnv(
+		[]EnvVar{{"A", "1"}, {"B", "2"}},
+		[]EnvVar{{"C", "3"}, {"A", "4"}},
+	)
+	want := []EnvVar{{"A", "4"}, {"B", "2"}, {"C", "3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeEnv = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,512 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// Metadata is stored as an append-only log of encrypted batches. Each batch
+// holds the records written by one operation, so an interrupted write loses
+// at most that operation. Replaying the log rebuilds an in-memory index,
+// which makes adding, removing and looking up a single file O(1) amortized
+// instead of rewriting all metadata on every change. The log is compacted
+// into a single batch once superseded records outweigh live ones.
+const (
+	recordKindHeader   = "header"
+	recordKindFile     = "file"
+	recordKindChunk    = "chunk"
+	recordKindSnapshot = "snapshot"
+	recordKindTrash    = "trash"
+	recordKindSecret   = "secret"
+	recordKindConfig   = "config"
+
+	recordOpPut    = "put"
+	recordOpDelete = "del"
+
+	// Compact once the log holds this many more records than live entries
+	compactSlack = 256
+)
+
+// metaRecord is a single change to the metadata
+type metaRecord struct {
+	Op    string          `json:"op"`
+	Kind  string          `json:"kind"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// metaHeader holds vault-wide metadata settings
+type metaHeader struct {
+	Version int `json:"version"`
+}
+
+// metaStore is the unlocked, indexed view of the vault metadata
+// If commit fails the in-memory state no longer matches the log and the
+// store must be discarded.
+type metaStore struct {
+	v       *Vault
+	key     []byte
+	version int
+	files   map[string]*storage.FileMetadata // by ID
+	byName  map[string]string                // OriginalName -> ID
+	chunks  map[string]storage.ChunkMetadata
+	// snapshots by ID
+	snapshots map[string]*storage.SnapshotMetadata
+	// removed files by trash entry ID
+	trash map[string]*storage.TrashEntry
+	// secret entries by name
+	secrets map[string]*storage.SecretMetadata
+	// vault settings, nil until first set
+	config *storage.VaultConfig
+
+	pending    []metaRecord
+	logRecords int // records in the on-disk log, live or superseded
+}
+
+// newMetaStore creates an empty metadata store
+func newMetaStore(v *Vault, key []byte) *metaStore {
+	return &metaStore{
+		v:       v,
+		key:     key,
+		version: metadataVersion,
+		files:   make(map[string]*storage.FileMetadata),
+		byName:  make(map[string]string),
+		chunks:  make(map[string]storage.ChunkMetadata),
+
+		snapshots: make(map[string]*storage.SnapshotMetadata),
+		trash:     make(map[string]*storage.TrashEntry),
+		secrets:   make(map[string]*storage.SecretMetadata),
+	}
+}
+
+// openMeta reads the metadata log, migrating single-blob metadata from older
+// vaults on first use
+func (v *Vault) openMeta(key []byte) (*metaStore, error) {
+	batches, err := storage.ReadMetadataLog(v.rootPath)
+	if err == storage.ErrVaultNotFound {
+		return v.migrateMetadata(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ms := newMetaStore(v, key)
+	for _, batch := range batches {
+		// Decrypt batch
+		plain, err := crypto.Decrypt(batch, key)
+		if err != nil {
+			return nil, err
+		}
+
+		var records []metaRecord
+		if err := json.Unmarshal(plain, &records); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+		for _, rec := range records {
+			if err := ms.apply(rec); err != nil {
+				return nil, err
+			}
+		}
+		ms.logRecords += len(records)
+	}
+
+	// The key is valid, so objects may now be read from and written to packs
+	if err := v.openPacks(key); err != nil {
+		return nil, err
+	}
+
+	return ms, nil
+}
+
+// migrateMetadata converts single-blob metadata into a metadata log
+func (v *Vault) migrateMetadata(key []byte) (*metaStore, error) {
+	meta, err := v.readLegacyMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := newMetaStore(v, key)
+	for chunkID, chunk := range meta.Chunks {
+		ms.putChunk(chunkID, chunk)
+	}
+	for _, f := range meta.Files {
+		// Files from before chunking are a single object; treat that object
+		// as the file's only chunk so all data is reference counted alike
+		if len(f.Chunks) == 0 {
+			f.Chunks = []string{f.ID}
+			ms.putChunk(f.ID, storage.ChunkMetadata{Size: f.Size, RefCount: 1})
+		}
+		ms.putFile(f)
+	}
+	ms.pending = nil
+
+	if err := ms.compact(); err != nil {
+		return nil, fmt.Errorf("failed to migrate metadata: %w", err)
+	}
+	if err := storage.DeleteMetadata(v.rootPath); err != nil {
+		return nil, err
+	}
+
+	if err := v.openPacks(key); err != nil {
+		return nil, err
+	}
+
+	return ms, nil
+}
+
+// readLegacyMetadata reads and decrypts single-blob metadata (format versions 1 and 2)
+func (v *Vault) readLegacyMetadata(key []byte) (*storage.VaultMetadata, error) {
+	encryptedMeta, err := storage.ReadMetadata(v.rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt metadata
+	plainMeta, err := crypto.Decrypt(encryptedMeta, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal JSON
+	var meta storage.VaultMetadata
+	if err := json.Unmarshal(plainMeta, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	return &meta, nil
+}
+
+// apply updates the in-memory index with a single record
+func (ms *metaStore) apply(rec metaRecord) error {
+	switch rec.Kind {
+	case recordKindHeader:
+		var header metaHeader
+		if err := json.Unmarshal(rec.Value, &header); err != nil {
+			return fmt.Errorf("failed to parse metadata header: %w", err)
+		}
+		ms.version = header.Version
+
+	case recordKindFile:
+		if old, exists := ms.files[rec.Key]; exists {
+			delete(ms.byName, old.OriginalName)
+		}
+		if rec.Op == recordOpDelete {
+			delete(ms.files, rec.Key)
+			return nil
+		}
+		var f storage.FileMetadata
+		if err := json.Unmarshal(rec.Value, &f); err != nil {
+			return fmt.Errorf("failed to parse file metadata: %w", err)
+		}
+		ms.files[rec.Key] = &f
+		ms.byName[f.OriginalName] = rec.Key
+
+	case recordKindChunk:
+		if rec.Op == recordOpDelete {
+			delete(ms.chunks, rec.Key)
+			return nil
+		}
+		var c storage.ChunkMetadata
+		if err := json.Unmarshal(rec.Value, &c); err != nil {
+			return fmt.Errorf("failed to parse chunk metadata: %w", err)
+		}
+		ms.chunks[rec.Key] = c
+
+	case recordKindSnapshot:
+		if rec.Op == recordOpDelete {
+			delete(ms.snapshots, rec.Key)
+			return nil
+		}
+		var snap storage.SnapshotMetadata
+		if err := json.Unmarshal(rec.Value, &snap); err != nil {
+			return fmt.Errorf("failed to parse snapshot metadata: %w", err)
+		}
+		ms.snapshots[rec.Key] = &snap
+
+	case recordKindTrash:
+		if rec.Op == recordOpDelete {
+			delete(ms.trash, rec.Key)
+			return nil
+		}
+		var entry storage.TrashEntry
+		if err := json.Unmarshal(rec.Value, &entry); err != nil {
+			return fmt.Errorf("failed to parse trash entry: %w", err)
+		}
+		ms.trash[rec.Key] = &entry
+
+	case recordKindSecret:
+		if rec.Op == recordOpDelete {
+			delete(ms.secrets, rec.Key)
+			return nil
+		}
+		var secret storage.SecretMetadata
+		if err := json.Unmarshal(rec.Value, &secret); err != nil {
+			return fmt.Errorf("failed to parse secret metadata: %w", err)
+		}
+		ms.secrets[rec.Key] = &secret
+
+	case recordKindConfig:
+		var config storage.VaultConfig
+		if err := json.Unmarshal(rec.Value, &config); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+		ms.config = &config
+
+	default:
+		return fmt.Errorf("unknown metadata record kind: %s", rec.Kind)
+	}
+
+	return nil
+}
+
+// stage records a change to be written on the next commit and applies it
+func (ms *metaStore) stage(op, kind, key string, value interface{}) {
+	rec := metaRecord{Op: op, Kind: kind, Key: key}
+	if value != nil {
+		// Metadata types always marshal successfully
+		rec.Value, _ = json.Marshal(value)
+	}
+	ms.pending = append(ms.pending, rec)
+	ms.apply(rec)
+}
+
+// putFile adds or replaces a file entry
+func (ms *metaStore) putFile(f storage.FileMetadata) {
+	ms.stage(recordOpPut, recordKindFile, f.ID, f)
+}
+
+// deleteFile removes a file entry
+func (ms *metaStore) deleteFile(id string) {
+	ms.stage(recordOpDelete, recordKindFile, id, nil)
+}
+
+// putChunk adds or updates a chunk entry
+func (ms *metaStore) putChunk(chunkID string, c storage.ChunkMetadata) {
+	ms.stage(recordOpPut, recordKindChunk, chunkID, c)
+}
+
+// deleteChunk removes a chunk entry
+func (ms *metaStore) deleteChunk(chunkID string) {
+	ms.stage(recordOpDelete, recordKindChunk, chunkID, nil)
+}
+
+// putSnapshot adds a snapshot entry
+func (ms *metaStore) putSnapshot(snap storage.SnapshotMetadata) {
+	ms.stage(recordOpPut, recordKindSnapshot, snap.ID, snap)
+}
+
+// deleteSnapshot removes a snapshot entry
+func (ms *metaStore) deleteSnapshot(id string) {
+	ms.stage(recordOpDelete, recordKindSnapshot, id, nil)
+}
+
+// putTrash adds a trash entry
+func (ms *metaStore) putTrash(entry storage.TrashEntry) {
+	ms.stage(recordOpPut, recordKindTrash, entry.ID, entry)
+}
+
+// deleteTrash removes a trash entry
+func (ms *metaStore) deleteTrash(id string) {
+	ms.stage(recordOpDelete, recordKindTrash, id, nil)
+}
+
+// putSecret adds or replaces a secret entry
+func (ms *metaStore) putSecret(secret storage.SecretMetadata) {
+	ms.stage(recordOpPut, recordKindSecret, secret.Name, secret)
+}
+
+// deleteSecret removes a secret entry
+func (ms *metaStore) deleteSecret(name string) {
+	ms.stage(recordOpDelete, recordKindSecret, name, nil)
+}
+
+// putConfig replaces the vault settings
+func (ms *metaStore) putConfig(config storage.VaultConfig) {
+	ms.stage(recordOpPut, recordKindConfig, "", config)
+}
+
+// commit encrypts the staged changes and appends them to the log as one batch
+func (ms *metaStore) commit() error {
+	if len(ms.pending) == 0 {
+		return nil
+	}
+
+	// Objects the batch refers to must be findable before it is committed
+	if err := ms.v.flushPacks(); err != nil {
+		return err
+	}
+
+	batch, err := ms.encryptBatch(ms.pending)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.AppendMetadataLog(ms.v.rootPath, batch); err != nil {
+		return err
+	}
+	ms.logRecords += len(ms.pending)
+	ms.pending = nil
+
+	// Rewrite the log once it is mostly superseded records
+	if ms.logRecords > 2*ms.liveRecords()+compactSlack {
+		return ms.compact()
+	}
+	return nil
+}
+
+// compact replaces the log with a single batch holding the live entries
+func (ms *metaStore) compact() error {
+	records := []metaRecord{}
+	add := func(kind, key string, value interface{}) {
+		raw, _ := json.Marshal(value)
+		records = append(records, metaRecord{Op: recordOpPut, Kind: kind, Key: key, Value: raw})
+	}
+
+	add(recordKindHeader, "", metaHeader{Version: ms.version})
+	for _, f := range ms.fileList() {
+		add(recordKindFile, f.ID, f)
+	}
+	for chunkID, c := range ms.chunks {
+		add(recordKindChunk, chunkID, c)
+	}
+	for _, snap := range ms.snapshotList() {
+		add(recordKindSnapshot, snap.ID, snap)
+	}
+	for _, entry := range ms.trashList() {
+		add(recordKindTrash, entry.ID, entry)
+	}
+	for _, secret := range ms.secretList() {
+		add(recordKindSecret, secret.Name, secret)
+	}
+	if ms.config != nil {
+		add(recordKindConfig, "", ms.config)
+	}
+
+	if err := ms.v.flushPacks(); err != nil {
+		return err
+	}
+
+	batch, err := ms.encryptBatch(records)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.WriteMetadataLog(ms.v.rootPath, [][]byte{batch}); err != nil {
+		return err
+	}
+	ms.logRecords = len(records)
+	return nil
+}
+
+// encryptBatch serializes and encrypts a batch of records
+func (ms *metaStore) encryptBatch(records []metaRecord) ([]byte, error) {
+	plain, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize metadata: %w", err)
+	}
+
+	encrypted, err := crypto.Encrypt(plain, ms.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt metadata: %w", err)
+	}
+	return encrypted, nil
+}
+
+// liveRecords returns the number of records a compacted log would hold
+func (ms *metaStore) liveRecords() int {
+	live := 1 + len(ms.files) + len(ms.chunks) + len(ms.snapshots) + len(ms.trash) + len(ms.secrets)
+	if ms.config != nil {
+		live++
+	}
+	return live
+}
+
+// fileList returns all files in the order they were added
+func (ms *metaStore) fileList() []storage.FileMetadata {
+	files := make([]storage.FileMetadata, 0, len(ms.files))
+	for _, f := range ms.files {
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].AddedAt.Equal(files[j].AddedAt) {
+			return files[i].AddedAt.Before(files[j].AddedAt)
+		}
+		return files[i].ID < files[j].ID
+	})
+	return files
+}
+
+// snapshotList returns all snapshots, oldest first
+func (ms *metaStore) snapshotList() []storage.SnapshotMetadata {
+	snapshots := make([]storage.SnapshotMetadata, 0, len(ms.snapshots))
+	for _, snap := range ms.snapshots {
+		snapshots = append(snapshots, *snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+		}
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots
+}
+
+// trashList returns all trash entries, oldest removal first
+func (ms *metaStore) trashList() []storage.TrashEntry {
+	entries := make([]storage.TrashEntry, 0, len(ms.trash))
+	for _, entry := range ms.trash {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// secretList returns all secret entries sorted by name
+func (ms *metaStore) secretList() []storage.SecretMetadata {
+	secrets := make([]storage.SecretMetadata, 0, len(ms.secrets))
+	for _, secret := range ms.secrets {
+		secrets = append(secrets, *secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets
+}
+
+// fileByName returns the file with exactly this name, or nil
+func (ms *metaStore) fileByName(name string) *storage.FileMetadata {
+	id, exists := ms.byName[name]
+	if !exists {
+		return nil
+	}
+	return ms.files[id]
+}
+
+// findFile looks a file up by name, falling back to fuzzy matching
+func (ms *metaStore) findFile(query string) *storage.FileMetadata {
+	if f := ms.fileByName(query); f != nil {
+		return f
+	}
+	return findFileByName(ms.fileList(), query)
+}
+
+// view returns the metadata as a single VaultMetadata value
+func (ms *metaStore) view() *storage.VaultMetadata {
+	chunks := make(map[string]storage.ChunkMetadata, len(ms.chunks))
+	for chunkID, c := range ms.chunks {
+		chunks[chunkID] = c
+	}
+	return &storage.VaultMetadata{
+		Version: ms.version,
+		Files:   ms.fileList(),
+		Chunks:  chunks,
+		Secrets: ms.secretList(),
+	}
+}
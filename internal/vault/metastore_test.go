@@ -0,0 +1,251 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// newTestVault creates a directory vault or container with an empty
+// metadata log, without stretching a password
+func newTestVault(t *testing.T, layout string) (*Vault, []byte) {
+	t.Helper()
+	rootPath := t.TempDir()
+	if layout == "container" {
+		rootPath = filepath.Join(rootPath, "vault"+storage.ContainerExt)
+	}
+	if err := storage.InitializeVault(rootPath); err != nil {
+		t.Fatalf("InitializeVault: %v", err)
+	}
+	key, err := crypto.GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(rootPath), key
+}
+
+// fileNames returns the sorted names of the files in the metadata
+func fileNames(ms *metaStore) []string {
+	var names []string
+	for name := range ms.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkFiles fails the test unless reopening the metadata gives these files
+func checkFiles(t *testing.T, v *Vault, key []byte, want ...string) *metaStore {
+	t.Helper()
+	ms, err := v.openMeta(key)
+	if err != nil {
+		t.Fatalf("openMeta: %v", err)
+	}
+	got := fileNames(ms)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+	}
+	return ms
+}
+
+func TestOpenMetaTornFinalBatch(t *testing.T) {
+	v, key := newTestVault(t, "directory")
+	ms := newMetaStore(v, key)
+	if err := ms.compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		ms.putFile(storage.FileMetadata{ID: name + "-id", OriginalName: name})
+		if err := ms.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Cut the last batch short, as an interrupted append would
+	logPath := storage.GetVaultPaths(v.rootPath).MetaLog
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(logPath, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	ms = checkFiles(t, v, key, "a.txt")
+
+	// The next commit overwrites the torn batch and both survive a replay
+	ms.putFile(storage.FileMetadata{ID: "c-id", OriginalName: "c.txt"})
+	if err := ms.commit(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, v, key, "a.txt", "c.txt")
+}
+
+func TestOpenMetaCorruptBatch(t *testing.T) {
+	v, key := newTestVault(t, "directory")
+	ms := newMetaStore(v, key)
+	if err := ms.compact(); err != nil {
+		t.Fatal(err)
+	}
+	ms.putFile(storage.FileMetadata{ID: "a-id", OriginalName: "a.txt"})
+	if err := ms.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A complete batch that fails to decrypt is an error, not a torn write
+	logPath := storage.GetVaultPaths(v.rootPath).MetaLog
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile(logPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.openMeta(key); err == nil {
+		t.Fatal("openMeta accepted a corrupted batch")
+	}
+}
+
+func TestMigrateLegacyMetadata(t *testing.T) {
+	for _, layout := range []string{"directory", "container"} {
+		for _, version := range []int{1, 2} {
+			t.Run(fmt.Sprintf("%s/v%d", layout, version), func(t *testing.T) {
+				testMigrateLegacyMetadata(t, layout, version)
+			})
+		}
+	}
+}
+
+// testMigrateLegacyMetadata writes single-blob metadata of the given version
+// and checks that opening the vault moves it to the metadata log
+func testMigrateLegacyMetadata(t *testing.T, layout string, version int) {
+	v, key := newTestVault(t, layout)
+	contents := map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"dir/b.txt": []byte("bravo bravo"),
+	}
+
+	writeObject := func(id string, data []byte) {
+		encrypted, err := crypto.Encrypt(data, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.WriteObject(v.rootPath, nil, id, encrypted); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta := storage.VaultMetadata{Version: version}
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		id := storage.GenerateObjectID(name)
+		f := storage.FileMetadata{ID: id, OriginalName: name, Size: int64(len(contents[name]))}
+		if version == 1 {
+			// Version 1 stores each file as a single object named by its ID
+			writeObject(id, contents[name])
+		} else {
+			chunkID := "chunk-" + id
+			writeObject(chunkID, contents[name])
+			f.Chunks = []string{chunkID}
+			f.SHA256 = storage.ContentHash(contents[name])
+			if meta.Chunks == nil {
+				meta.Chunks = make(map[string]storage.ChunkMetadata)
+			}
+			meta.Chunks[chunkID] = storage.ChunkMetadata{Size: f.Size, RefCount: 1}
+		}
+		meta.Files = append(meta.Files, f)
+	}
+
+	plain, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := crypto.Encrypt(plain, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.WriteMetadata(v.rootPath, encrypted); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := checkFiles(t, v, key, "a.txt", "dir/b.txt")
+	if ms.version != metadataVersion {
+		t.Errorf("version = %d, want %d", ms.version, metadataVersion)
+	}
+	if len(ms.chunks) != 2 {
+		t.Errorf("%d chunks, want 2", len(ms.chunks))
+	}
+	for _, c := range ms.chunks {
+		if c.RefCount != 1 {
+			t.Errorf("chunk refcount = %d, want 1", c.RefCount)
+		}
+	}
+
+	// The blob is gone and the log replays to the same files
+	if _, err := storage.ReadMetadata(v.rootPath); err != storage.ErrVaultNotFound {
+		t.Errorf("ReadMetadata after migration: err = %v", err)
+	}
+	ms = checkFiles(t, v, key, "a.txt", "dir/b.txt")
+	for name, want := range contents {
+		data, err := v.loadFileData(key, ms.fileByName(name))
+		if err != nil {
+			t.Fatalf("v%d %s: %v", version, name, err)
+		}
+		if string(data) != string(want) {
+			t.Errorf("v%d %s = %q, want %q", version, name, data, want)
+		}
+	}
+}
+
+func TestConfigStoredInMetadata(t *testing.T) {
+	v, key := newTestVault(t, "container")
+	ms := newMetaStore(v, key)
+	if err := ms.compact(); err != nil {
+		t.Fatal(err)
+	}
+	session := &Session{vault: v, masterKey: key}
+
+	config, err := session.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *config != *storage.DefaultConfig() {
+		t.Fatalf("default config = %+v", config)
+	}
+
+	if err := session.SetConfig(&storage.VaultConfig{Wipe: "sometimes"}); err == nil {
+		t.Fatal("SetConfig accepted an invalid wipe method")
+	}
+	if err := session.SetConfig(&storage.VaultConfig{TrashRetention: "7d", Wipe: "none"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The settings survive a replay and a compaction
+	ms, err = v.openMeta(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.compact(); err != nil {
+		t.Fatal(err)
+	}
+	config, err = session.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.TrashRetention != "7d" || config.Wipe != "none" {
+		t.Fatalf("config = %+v", config)
+	}
+}
@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// packIndexPurpose is the subkey purpose for encrypting the pack index
+const packIndexPurpose = "pack-index"
+
+// Repack compacts pack files after removals and packs small loose objects
+func (s *Session) Repack() (*storage.RepackResult, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.openPacks(masterKey); err != nil {
+		return nil, err
+	}
+
+	return storage.Repack(v.rootPath, v.packs)
+}
+
+// openPacks enables pack storage so small objects are written to pack files
+// If packs are already open, the cached index is refreshed instead.
+func (v *Vault) openPacks(masterKey []byte) error {
+	if v.packs != nil {
+		v.packs.Refresh()
+		return nil
+	}
+
+	indexKey, err := crypto.DeriveSubkey(masterKey, packIndexPurpose)
+	if err != nil {
+		return err
+	}
+	defer clear(indexKey)
+
+	v.packs = storage.OpenPacks(v.rootPath, indexKey)
+	return nil
+}
+
+// flushPacks saves the pack index changes of the current operation
+func (v *Vault) flushPacks() error {
+	if v.packs == nil {
+		return nil
+	}
+	return v.packs.Flush()
+}
+
+// closePacks zeroes the pack index key
+func (v *Vault) closePacks() {
+	if v.packs != nil {
+		v.packs.Close()
+		v.packs = nil
+	}
+}
@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// templateResolver looks up vault contents for a template, decrypting each
+// file at most once
+type templateResolver struct {
+	v         *Vault
+	masterKey []byte
+	ms        *metaStore
+	files     map[string][]byte
+	envs      map[string]map[string]string
+	secrets   map[string]*storage.SecretFields
+	// missing collects unresolved references instead of failing, if not nil
+	missing map[string]bool
+}
+
+// contents returns the decrypted contents of a file, found by its exact name
+func (r *templateResolver) contents(name string) ([]byte, error) {
+	if data, ok := r.files[name]; ok {
+		return data, nil
+	}
+
+	fileMeta := r.ms.fileByName(name)
+	if fileMeta == nil {
+		if r.missing != nil {
+			r.missing[fmt.Sprintf("file %q", name)] = true
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+	}
+	if fileMeta.IsSymlink() {
+		return nil, fmt.Errorf("%s: %w", name, ErrSymlink)
+	}
+
+	data, err := r.v.loadFileData(r.masterKey, fileMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	r.files[name] = data
+	return data, nil
+}
+
+// dotenv returns the value of a variable in a vaulted dotenv file
+func (r *templateResolver) dotenv(name, key string) (string, error) {
+	vars, ok := r.envs[name]
+	if !ok {
+		data, err := r.contents(name)
+		if err != nil || data == nil {
+			return "", err
+		}
+		parsed, err := ParseDotenv(data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		vars = make(map[string]string)
+		for _, ev := range parsed {
+			vars[ev.Key] = ev.Value
+		}
+		r.envs[name] = vars
+	}
+
+	value, ok := vars[key]
+	if !ok {
+		if r.missing != nil {
+			r.missing[fmt.Sprintf("variable %s in %q", key, name)] = true
+			return "", nil
+		}
+		return "", fmt.Errorf("variable %s not found in %s", key, name)
+	}
+	return value, nil
+}
+
+// secret returns a vaulted file's contents without trailing newlines, for
+// files holding a single value
+// If no file has that name, "entry.field" names a field of a secret entry.
+func (r *templateResolver) secret(name string) (string, error) {
+	if r.ms.fileByName(name) == nil {
+		if i := strings.LastIndex(name, "."); i > 0 {
+			if entry, exists := r.ms.secrets[name[:i]]; exists {
+				return r.secretField(entry, name[i+1:])
+			}
+		}
+	}
+
+	data, err := r.contents(name)
+	return strings.TrimRight(string(data), "\r\n"), err
+}
+
+// secretField returns a field of a secret entry
+func (r *templateResolver) secretField(entry *storage.SecretMetadata, field string) (string, error) {
+	fields, ok := r.secrets[entry.Name]
+	if !ok {
+		var err error
+		fields, err = r.v.readSecretFields(r.masterKey, entry)
+		if err != nil {
+			return "", err
+		}
+		r.secrets[entry.Name] = fields
+	}
+
+	value, ok := fields.Get(field)
+	if !ok {
+		if r.missing != nil {
+			r.missing[fmt.Sprintf("field %s of secret %q", field, entry.Name)] = true
+			return "", nil
+		}
+		return "", fmt.Errorf("secret %s has no field %s", entry.Name, field)
+	}
+	return value, nil
+}
+
+// funcs returns the template functions
+func (r *templateResolver) funcs() template.FuncMap {
+	return template.FuncMap{
+		// file returns a vaulted file's contents exactly
+		"file": func(name string) (string, error) {
+			data, err := r.contents(name)
+			return string(data), err
+		},
+		"secret": r.secret,
+		// dotenv returns a variable from a vaulted dotenv file
+		"dotenv": r.dotenv,
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"b64dec": func(s string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return "", fmt.Errorf("b64dec: %w", err)
+			}
+			return string(data), nil
+		},
+		"quote": strconv.Quote,
+		"trim":  strings.TrimSpace,
+		// indent prefixes every line with n spaces
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+	}
+}
+
+// newTemplateResolver reads the metadata for resolving template references
+func (s *Session) newTemplateResolver() (*templateResolver, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &templateResolver{
+		v:         v,
+		masterKey: masterKey,
+		ms:        ms,
+		files:     make(map[string][]byte),
+		envs:      make(map[string]map[string]string),
+		secrets:   make(map[string]*storage.SecretFields),
+	}, nil
+}
+
+// clear zeroes the decrypted contents held by the resolver
+func (r *templateResolver) clear() {
+	for _, data := range r.files {
+		clear(data)
+	}
+}
+
+// RenderTemplate executes a text/template with functions resolving vault
+// contents and writes the output to w
+// The functions are file, secret and dotenv, which read vaulted files by their
+// exact names (secret also reads fields of secret entries), and the helpers
+// b64enc, b64dec, quote, trim and indent. Nothing is written to w unless the
+// whole template executes, so a missing reference never produces partial
+// output.
+func (s *Session) RenderTemplate(name, text string, w io.Writer) error {
+	r, err := s.newTemplateResolver()
+	if err != nil {
+		return err
+	}
+	defer r.clear()
+
+	tmpl, err := template.New(name).Funcs(r.funcs()).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return err
+	}
+	defer clear(out.Bytes())
+
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// CheckTemplate executes a template as RenderTemplate does, discarding the
+// output, and returns the references it makes that the vault cannot resolve,
+// sorted
+func (s *Session) CheckTemplate(name, text string) ([]string, error) {
+	r, err := s.newTemplateResolver()
+	if err != nil {
+		return nil, err
+	}
+	defer r.clear()
+	r.missing = make(map[string]bool)
+
+	tmpl, err := template.New(name).Funcs(r.funcs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, nil); err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0, len(r.missing))
+	for ref := range r.missing {
+		missing = append(missing, ref)
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
@@ -0,0 +1,185 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// A secret entry is a named set of fields (username, password, URL, notes
+// and custom fields) kept alongside the files. The entry is recorded in the
+// metadata; its field values are stored as an encrypted object, replaced
+// whole on every change. Entries are not part of snapshots.
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SetSecret creates a secret entry or changes an existing one
+// Each field in fields is set; an empty value removes the field. An empty
+// secretType keeps the entry's type, or makes a new entry a login.
+func (s *Session) SetSecret(name string, secretType storage.SecretType, fields map[string]string) (*storage.SecretMetadata, error) {
+	if err := storage.ValidateFileName(name); err != nil {
+		return nil, err
+	}
+	for field := range fields {
+		if err := storage.ValidateSecretField(field); err != nil {
+			return nil, err
+		}
+	}
+
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var secret storage.SecretMetadata
+	var values storage.SecretFields
+	var replaced []string
+	if existing, exists := ms.secrets[name]; exists {
+		secret = *existing
+		current, err := v.readSecretFields(masterKey, existing)
+		if err != nil {
+			return nil, err
+		}
+		values = *current
+		replaced = []string{existing.ObjectID}
+	} else {
+		secret = storage.SecretMetadata{
+			ID:        storage.GenerateObjectID("secret-" + name)[:8],
+			Name:      name,
+			Type:      storage.SecretLogin,
+			CreatedAt: now,
+		}
+	}
+	if secretType != "" {
+		secret.Type = secretType
+	}
+
+	for field, value := range fields {
+		values.Set(field, value)
+	}
+	secret.FieldNames = values.Names()
+	secret.UpdatedAt = now
+	secret.ObjectID = storage.GenerateObjectID("secret-fields-" + name)
+
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize secret: %w", err)
+	}
+	encrypted, err := crypto.Encrypt(plain, masterKey)
+	clear(plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	if err := storage.WriteObject(v.rootPath, v.packs, secret.ObjectID, encrypted); err != nil {
+		return nil, fmt.Errorf("failed to write secret: %w", err)
+	}
+
+	ms.putSecret(secret)
+	if err := ms.commit(); err != nil {
+		v.deleteObjects([]string{secret.ObjectID})
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// The previous values are no longer referenced
+	v.deleteObjects(replaced)
+	return &secret, nil
+}
+
+// GetSecret returns a secret entry and its field values
+func (s *Session) GetSecret(name string) (*storage.SecretMetadata, *storage.SecretFields, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secret, exists := ms.secrets[name]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+
+	fields, err := v.readSecretFields(masterKey, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, fields, nil
+}
+
+// ListSecrets returns all secret entries sorted by name, without their values
+func (s *Session) ListSecrets() ([]storage.SecretMetadata, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.secretList(), nil
+}
+
+// RemoveSecret deletes a secret entry and its values permanently
+func (s *Session) RemoveSecret(name string) error {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return err
+	}
+
+	secret, exists := ms.secrets[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	objectID := secret.ObjectID
+
+	ms.deleteSecret(name)
+	if err := ms.commit(); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	v.deleteObjects([]string{objectID})
+	return nil
+}
+
+// readSecretFields reads and decrypts the field values of a secret entry
+func (v *Vault) readSecretFields(masterKey []byte, secret *storage.SecretMetadata) (*storage.SecretFields, error) {
+	encrypted, err := storage.ReadObject(v.rootPath, v.packs, secret.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s: %w", secret.Name, err)
+	}
+	plain, err := crypto.Decrypt(encrypted, masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s: %w", secret.Name, err)
+	}
+	defer clear(plain)
+
+	var fields storage.SecretFields
+	if err := json.Unmarshal(plain, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse secret %s: %w", secret.Name, err)
+	}
+	return &fields, nil
+}
@@ -0,0 +1,157 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// ErrSessionClosed is returned by the methods of a session after Close
+var ErrSessionClosed = errors.New("vault session is closed")
+
+// Credential unlocks a vault's master key
+// Password, RecoveryKey and UnlockedKey are the credentials accepted; all of
+// them give the same Session.
+type Credential interface {
+	unlock(v *Vault) ([]byte, error)
+}
+
+// Password unlocks a vault with its password
+type Password string
+
+// unlock derives the password key and decrypts the master key with it
+func (p Password) unlock(v *Vault) ([]byte, error) {
+	// Read salt
+	salt, err := storage.ReadSalt(v.rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read encrypted master key
+	encryptedMasterKey, err := storage.ReadMasterKey(v.rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt master key
+	return crypto.DecryptMasterKey(encryptedMasterKey, string(p), salt)
+}
+
+// RecoveryKey unlocks a vault with the recovery key given out by Initialize
+type RecoveryKey []byte
+
+// unlock decrypts the master key with the recovery key
+func (k RecoveryKey) unlock(v *Vault) ([]byte, error) {
+	// Read encrypted master key (for recovery)
+	encryptedMasterKeyForRecovery, err := storage.ReadRecoveryKey(v.rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt master key
+	return crypto.DecryptMasterKeyWithRecoveryKey(encryptedMasterKeyForRecovery, k)
+}
+
+// UnlockedKey is a master key unlocked earlier, such as one held by the agent
+// The session works on a copy, so the caller may zero it afterwards.
+type UnlockedKey []byte
+
+// unlock checks that the key decrypts the vault's metadata and returns a
+// copy of it
+func (k UnlockedKey) unlock(v *Vault) ([]byte, error) {
+	if err := v.verifyKey(k); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), k...), nil
+}
+
+// verifyKey checks that key is the vault's master key by decrypting the
+// first metadata batch, or the single-blob metadata of older vaults
+// Returns crypto.ErrInvalidPassword if it is not.
+func (v *Vault) verifyKey(key []byte) error {
+	var encryptedMeta []byte
+	batches, err := storage.ReadMetadataLog(v.rootPath)
+	switch {
+	case err == storage.ErrVaultNotFound:
+		encryptedMeta, err = storage.ReadMetadata(v.rootPath)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case len(batches) == 0:
+		return fmt.Errorf("failed to verify master key: %w", storage.ErrVaultNotFound)
+	default:
+		encryptedMeta = batches[0]
+	}
+
+	if _, err := crypto.Decrypt(encryptedMeta, key); err != nil {
+		if err == crypto.ErrCorruptedData {
+			return err
+		}
+		// A key of the wrong length is just as wrong
+		return crypto.ErrInvalidPassword
+	}
+	return nil
+}
+
+// Session is an unlocked vault, holding its master key until Close
+// A session is not safe for concurrent use.
+type Session struct {
+	vault     *Vault
+	masterKey []byte
+}
+
+// Unlock decrypts the vault's master key with a credential and returns a
+// session for working with the vault's contents
+// The caller must Close the session once done with it.
+func (v *Vault) Unlock(credential Credential) (*Session, error) {
+	masterKey, err := credential.unlock(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session gets its own handle on the vault, holding its pack index
+	return &Session{vault: &Vault{rootPath: v.rootPath, onProgress: v.onProgress}, masterKey: masterKey}, nil
+}
+
+// Close zeroes the session's copy of the master key and the pack index key
+// Methods called after Close return ErrSessionClosed. Close may be called
+// more than once.
+func (s *Session) Close() error {
+	clear(s.masterKey)
+	s.masterKey = nil
+	s.vault.closePacks()
+	return nil
+}
+
+// Vault returns the vault the session unlocked
+func (s *Session) Vault() *Vault {
+	return s.vault
+}
+
+// AgentKey returns a copy of the session's master key, for handing over to
+// the agent and nothing else: the key must never leave the agent path, so
+// pkg/vaultix does not expose it
+func (s *Session) AgentKey() ([]byte, error) {
+	if s.masterKey == nil {
+		return nil, ErrSessionClosed
+	}
+	return append([]byte(nil), s.masterKey...), nil
+}
+
+// SetProgressCallback sets a callback function for reporting progress
+func (s *Session) SetProgressCallback(callback func(current, total int, message string)) {
+	s.vault.SetProgressCallback(callback)
+}
+
+// unlocked returns the vault and master key for an operation, or
+// ErrSessionClosed
+func (s *Session) unlocked() (*Vault, []byte, error) {
+	if s.masterKey == nil {
+		return nil, nil, ErrSessionClosed
+	}
+	return s.vault, s.masterKey, nil
+}
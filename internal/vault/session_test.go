@@ -0,0 +1,53 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+)
+
+// newTestSession returns an unlocked session on a new, empty vault
+func newTestSession(t *testing.T, layout string) *Session {
+	t.Helper()
+	v, key := newTestVault(t, layout)
+	if err := newMetaStore(v, key).compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	session := &Session{vault: v, masterKey: key}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestUnlockedKeyVerified(t *testing.T) {
+	for _, layout := range []string{"directory", "container"} {
+		v, key := newTestVault(t, layout)
+		if err := newMetaStore(v, key).compact(); err != nil {
+			t.Fatalf("%s: compact: %v", layout, err)
+		}
+
+		wrongKey, err := crypto.GenerateMasterKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name    string
+			key     []byte
+			wantErr error
+		}{
+			{"master key", key, nil},
+			{"other key", wrongKey, crypto.ErrInvalidPassword},
+			{"short key", key[:7], crypto.ErrInvalidPassword},
+			{"empty key", nil, crypto.ErrInvalidPassword},
+		}
+		for _, tt := range tests {
+			session, err := v.Unlock(UnlockedKey(tt.key))
+			if err != tt.wantErr {
+				t.Errorf("%s: %s: Unlock err = %v, want %v", layout, tt.name, err, tt.wantErr)
+			}
+			if session != nil {
+				session.Close()
+			}
+		}
+	}
+}
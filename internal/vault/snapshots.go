@@ -0,0 +1,252 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// A snapshot is an immutable copy of the file list at one point in time. The
+// list is stored as an encrypted object and the snapshot holds a reference on
+// every chunk it lists, so removing or clearing files never deletes data a
+// snapshot still needs.
+
+var (
+	ErrSnapshotNotFound  = errors.New("snapshot not found")
+	ErrSnapshotAmbiguous = errors.New("snapshot ID prefix matches more than one snapshot")
+)
+
+// CreateSnapshot records the current files as a new snapshot
+func (s *Session) CreateSnapshot(description string) (*storage.SnapshotMetadata, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := v.createSnapshotInternal(ms, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ms.commit(); err != nil {
+		v.deleteObjects([]string{snap.ObjectID})
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	return snap, nil
+}
+
+// newSnapshotID returns a short snapshot ID not taken by another snapshot
+func (ms *metaStore) newSnapshotID() string {
+	for {
+		id := storage.GenerateObjectID("snapshot")[:8]
+		if _, exists := ms.snapshots[id]; !exists {
+			return id
+		}
+	}
+}
+
+// createSnapshotInternal writes the current file list and stages the snapshot
+// entry and its chunk references (the caller commits)
+func (v *Vault) createSnapshotInternal(ms *metaStore, description string) (*storage.SnapshotMetadata, error) {
+	files := ms.fileList()
+
+	plain, err := json.Marshal(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+	encrypted, err := crypto.Encrypt(plain, ms.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt snapshot: %w", err)
+	}
+
+	snap := storage.SnapshotMetadata{
+		ID:          ms.newSnapshotID(),
+		Description: description,
+		CreatedAt:   time.Now(),
+		FileCount:   len(files),
+		ObjectID:    storage.GenerateObjectID("snapshot-files"),
+	}
+	for i := range files {
+		snap.TotalSize += files[i].Size
+	}
+
+	if err := storage.WriteObject(v.rootPath, v.packs, snap.ObjectID, encrypted); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	for i := range files {
+		ms.retainFile(&files[i])
+	}
+	ms.putSnapshot(snap)
+
+	return &snap, nil
+}
+
+// ListSnapshots returns all snapshots, oldest first
+func (s *Session) ListSnapshots() ([]storage.SnapshotMetadata, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.snapshotList(), nil
+}
+
+// RestoreSnapshot replaces the current files with those of a snapshot
+// The current files are saved as a new snapshot first, so a restore can
+// itself be undone. Returns that snapshot.
+func (s *Session) RestoreSnapshot(snapshotID string) (*storage.SnapshotMetadata, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := ms.findSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := v.loadSnapshotFiles(ms, snap)
+	if err != nil {
+		return nil, err
+	}
+
+	backup, err := v.createSnapshotInternal(ms, "before restoring snapshot "+snap.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take references on the restored files before dropping the current ones,
+	// so chunks shared by both never reach zero. The backup snapshot holds the
+	// current files' chunks, so releasing them frees nothing.
+	for i := range files {
+		ms.retainFile(&files[i])
+	}
+	for _, f := range ms.fileList() {
+		ms.releaseFile(&f)
+		ms.deleteFile(f.ID)
+	}
+	for _, f := range files {
+		ms.putFile(f)
+	}
+
+	if err := ms.commit(); err != nil {
+		v.deleteObjects([]string{backup.ObjectID})
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	return backup, nil
+}
+
+// DeleteSnapshot removes a snapshot and any data only it referenced
+func (s *Session) DeleteSnapshot(snapshotID string) (*storage.SnapshotMetadata, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := ms.findSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := v.loadSnapshotFiles(ms, snap)
+	if err != nil {
+		return nil, err
+	}
+
+	unreferenced := []string{snap.ObjectID}
+	for i := range files {
+		unreferenced = append(unreferenced, ms.releaseFile(&files[i])...)
+	}
+	ms.deleteSnapshot(snap.ID)
+
+	if err := ms.commit(); err != nil {
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
+	return snap, nil
+}
+
+// findSnapshot looks a snapshot up by ID or unique ID prefix
+func (ms *metaStore) findSnapshot(query string) (*storage.SnapshotMetadata, error) {
+	if snap, exists := ms.snapshots[query]; exists {
+		return snap, nil
+	}
+
+	var match *storage.SnapshotMetadata
+	for id, snap := range ms.snapshots {
+		if query != "" && strings.HasPrefix(id, query) {
+			if match != nil {
+				return nil, ErrSnapshotAmbiguous
+			}
+			match = snap
+		}
+	}
+	if match == nil {
+		return nil, ErrSnapshotNotFound
+	}
+	return match, nil
+}
+
+// loadSnapshotFiles reads and decrypts a snapshot's file list
+func (v *Vault) loadSnapshotFiles(ms *metaStore, snap *storage.SnapshotMetadata) ([]storage.FileMetadata, error) {
+	encrypted, err := storage.ReadObject(v.rootPath, v.packs, snap.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", snap.ID, err)
+	}
+
+	plain, err := crypto.Decrypt(encrypted, ms.key)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []storage.FileMetadata
+	if err := json.Unmarshal(plain, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", snap.ID, err)
+	}
+	return files, nil
+}
+
+// extractSource returns the files to extract from for the given options
+func (v *Vault) extractSource(ms *metaStore, opts ExtractOptions) ([]storage.FileMetadata, error) {
+	if opts.Snapshot == "" {
+		return ms.fileList(), nil
+	}
+
+	snap, err := ms.findSnapshot(opts.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return v.loadSnapshotFiles(ms, snap)
+}
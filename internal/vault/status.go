@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// FileState classifies a file when comparing a directory with the vault
+type FileState string
+
+const (
+	StateUnchanged FileState = "unchanged"  // on disk and identical to the vaulted copy
+	StateModified  FileState = "modified"   // on disk and different from the vaulted copy
+	StateNew       FileState = "new"        // on disk but not in the vault
+	StateVaultOnly FileState = "vault-only" // in the vault but not on disk
+	StateNotFile   FileState = "not-a-file" // on disk as something else, such as a directory
+)
+
+// FileStatus is the state of one file when comparing a directory with the vault
+type FileStatus struct {
+	Name  string // vault name, the path relative to the directory
+	Path  string // path on disk
+	State FileState
+}
+
+// Status compares the files in a directory with the vault, matching each
+// vaulted file with the file at its name below dir
+// A file whose size and modification time match the vault is unchanged
+// without being read; otherwise its hash is compared. Files on disk that are
+// not in the vault are found the way init finds them, so hidden and ignored
+// files are not reported as new. Results are sorted by name.
+func (s *Session) Status(dir string) ([]FileStatus, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.statusInternal(masterKey, ms, dir)
+}
+
+// statusInternal is the internal implementation of Status
+func (v *Vault) statusInternal(masterKey []byte, ms *metaStore, dir string) ([]FileStatus, error) {
+	var statuses []FileStatus
+	for _, f := range ms.fileList() {
+		localPath := filepath.Join(dir, filepath.FromSlash(f.OriginalName))
+		state, err := v.localState(masterKey, &f, localPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", f.OriginalName, err)
+		}
+		statuses = append(statuses, FileStatus{Name: f.OriginalName, Path: localPath, State: state})
+	}
+
+	files, err := storage.ListDirectoryTree(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, filePath := range files {
+		name, err := treeName(dir, "", filePath)
+		if err != nil {
+			return nil, err
+		}
+		if ms.fileByName(name) == nil {
+			statuses = append(statuses, FileStatus{Name: name, Path: filePath, State: StateNew})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// localState classifies the file on disk for a vaulted file
+func (v *Vault) localState(masterKey []byte, fileMeta *storage.FileMetadata, localPath string) (FileState, error) {
+	info, err := os.Lstat(localPath)
+	if os.IsNotExist(err) {
+		return StateVaultOnly, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		return StateNotFile, nil
+	}
+
+	// Quick check, as extraction restores the modification time
+	if !fileMeta.IsSymlink() && info.Mode().IsRegular() &&
+		info.Size() == fileMeta.Size && info.ModTime().Equal(fileMeta.ModTime) {
+		return StateUnchanged, nil
+	}
+
+	match, _, err := v.compareLocal(masterKey, fileMeta, localPath)
+	if err != nil {
+		return "", err
+	}
+	if match {
+		return StateUnchanged, nil
+	}
+	return StateModified, nil
+}
@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatusNotFile(t *testing.T) {
+	session := newTestSession(t, "directory")
+	for _, name := range []string{"dir.txt", "edited.txt"} {
+		if _, err := session.AddReader(name, strings.NewReader("stored "+name)); err != nil {
+			t.Fatalf("AddReader %s: %v", name, err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "dir.txt"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "edited.txt"), []byte("local edit"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := session.Status(dir)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	want := map[string]FileState{"dir.txt": StateNotFile, "edited.txt": StateModified}
+	for _, st := range statuses {
+		if want[st.Name] != st.State {
+			t.Errorf("Status %s = %s, want %s", st.Name, st.State, want[st.Name])
+		}
+	}
+
+	// The directory is skipped and reported, and the other file is still updated
+	results, err := session.UpdateAll(dir)
+	if err != nil {
+		t.Fatalf("UpdateAll: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("UpdateAll returned %d results, want 2", len(results))
+	}
+	if results[0].Name != "edited.txt" || !results[0].Changed || results[0].Version != 2 {
+		t.Errorf("UpdateAll result %+v, want edited.txt updated to version 2", results[0])
+	}
+	if results[1].Name != "dir.txt" || !results[1].NotFile || results[1].Changed {
+		t.Errorf("UpdateAll result %+v, want dir.txt skipped as not a file", results[1])
+	}
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// ReadFile decrypts a vaulted file and writes its contents to w, without
+// writing anything to disk
+// The file is found as in ExtractFile; opts.Snapshot and opts.Version select
+// earlier contents. Nothing is written to w unless the whole file decrypts and
+// matches its stored hash. Symbolic links have no contents to write and are
+// refused with ErrSymlink.
+func (s *Session) ReadFile(fileName string, w io.Writer, opts ExtractOptions) error {
+	contents, err := s.ReadFiles([]string{fileName}, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(contents[0]); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fileName, err)
+	}
+	return nil
+}
+
+// ReadFiles decrypts several vaulted files into memory, reading the metadata
+// once, and returns their contents in the order of fileNames
+// Files are found as in ReadFile.
+func (s *Session) ReadFiles(fileNames []string, opts ExtractOptions) ([][]byte, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([][]byte, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		fileMeta, err := v.lookupFile(ms, fileName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+		if fileMeta.IsSymlink() {
+			return nil, fmt.Errorf("%s: %w", fileMeta.OriginalName, ErrSymlink)
+		}
+
+		// Read and decrypt file contents
+		plaintext, err := v.loadFileData(masterKey, fileMeta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", fileMeta.OriginalName, err)
+		}
+		contents = append(contents, plaintext)
+	}
+
+	return contents, nil
+}
+
+// AddReader encrypts everything read from r and stores it under name, as a
+// new file or as the next version of an existing one
+// A new file is stored without attributes, so it is extracted with mode 0600;
+// a new version keeps the attributes of the current one. The modification
+// time is the time of adding. Returns the version number stored.
+func (s *Session) AddReader(name string, r io.Reader) (int, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return 0, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	var attrs storage.FileAttributes
+	if existing := ms.fileByName(name); existing != nil && !existing.IsSymlink() {
+		attrs = existing.FileAttributes
+	}
+
+	return v.storeContents(ms, name, data, time.Now(), attrs)
+}
@@ -0,0 +1,235 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// Removed files are moved into the trash instead of being deleted. A trash
+// entry keeps the file's metadata and takes over its chunk references, so
+// moving a file to the trash and back never touches the stored data. Entries
+// older than the vault's trash retention are deleted for good the next time
+// files are removed or the trash is listed.
+
+var ErrNotInTrash = errors.New("file not found in trash")
+
+// discardFile stages removing a file, either into the trash or permanently
+// Returns the objects no longer referenced, to be deleted after the commit
+func (ms *metaStore) discardFile(fileMeta *storage.FileMetadata, permanent bool) []string {
+	ms.deleteFile(fileMeta.ID)
+	if permanent {
+		return ms.releaseFile(fileMeta)
+	}
+
+	ms.putTrash(storage.TrashEntry{
+		ID:        ms.newTrashID(fileMeta.ID),
+		DeletedAt: time.Now(),
+		File:      *fileMeta,
+	})
+	return nil
+}
+
+// newTrashID returns a short trash entry ID not taken by another entry
+func (ms *metaStore) newTrashID(fileID string) string {
+	for {
+		id := storage.GenerateObjectID("trash-" + fileID)[:8]
+		if _, exists := ms.trash[id]; !exists {
+			return id
+		}
+	}
+}
+
+// expireTrash permanently deletes trash entries older than the retention
+// set in the vault config
+func (v *Vault) expireTrash(ms *metaStore) error {
+	if len(ms.trash) == 0 {
+		return nil
+	}
+
+	retention, err := ParseRetention(ms.vaultConfig().TrashRetention)
+	if err != nil {
+		return fmt.Errorf("invalid trash retention in config: %w", err)
+	}
+	if retention == 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-retention)
+	var unreferenced []string
+	for _, entry := range ms.trashList() {
+		if entry.DeletedAt.Before(cutoff) {
+			unreferenced = append(unreferenced, ms.releaseFile(&entry.File)...)
+			ms.deleteTrash(entry.ID)
+		}
+	}
+
+	if err := ms.commit(); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
+	return nil
+}
+
+// ListTrash returns the files in the trash, oldest removal first
+func (s *Session) ListTrash() ([]storage.TrashEntry, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.expireTrash(ms); err != nil {
+		return nil, err
+	}
+
+	return ms.trashList(), nil
+}
+
+// RestoreFromTrash moves a file from the trash back into the vault
+// The query is a trash entry ID or an exact file name; if the file was
+// removed more than once, the most recent removal is restored.
+func (s *Session) RestoreFromTrash(query string) (*storage.TrashEntry, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := ms.trashEntry(query)
+	if entry == nil {
+		return nil, ErrNotInTrash
+	}
+	if ms.fileByName(entry.File.OriginalName) != nil {
+		return nil, fmt.Errorf("%w: %s (remove or rename it first)", ErrFileAlreadyExists, entry.File.OriginalName)
+	}
+
+	restored := entry.File
+	if _, exists := ms.files[restored.ID]; exists {
+		// The same file came back another way, e.g. from a snapshot
+		restored.ID = storage.GenerateObjectID(restored.OriginalName)
+	}
+
+	ms.deleteTrash(entry.ID)
+	ms.putFile(restored)
+
+	if err := ms.commit(); err != nil {
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	return entry, nil
+}
+
+// FindTrash returns the trash entries a query could mean, newest removal
+// first, for resolving it before RestoreFromTrash
+// An entry ID or exact file name gives only the entry RestoreFromTrash would
+// restore; otherwise the entries whose ID starts with the query, or else
+// whose name matches it fuzzily, are returned.
+func (s *Session) FindTrash(query string) ([]storage.TrashEntry, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.matchTrash(query), nil
+}
+
+// EmptyTrash permanently deletes every file in the trash
+// Returns the number of files deleted
+func (s *Session) EmptyTrash() (int, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return 0, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return 0, err
+	}
+
+	var unreferenced []string
+	entries := ms.trashList()
+	for i := range entries {
+		unreferenced = append(unreferenced, ms.releaseFile(&entries[i].File)...)
+		ms.deleteTrash(entries[i].ID)
+	}
+
+	if err := ms.commit(); err != nil {
+		return 0, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
+	return len(entries), nil
+}
+
+// trashEntry returns the entry with this ID, or else the most recent
+// removal of the file with exactly this name, or nil
+func (ms *metaStore) trashEntry(query string) *storage.TrashEntry {
+	if entry, exists := ms.trash[query]; exists {
+		return entry
+	}
+
+	entries := ms.trashList()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].File.OriginalName == query {
+			return ms.trash[entries[i].ID]
+		}
+	}
+	return nil
+}
+
+// matchTrash returns the entries a query could mean, newest removal first
+func (ms *metaStore) matchTrash(query string) []storage.TrashEntry {
+	if entry := ms.trashEntry(query); entry != nil {
+		return []storage.TrashEntry{*entry}
+	}
+
+	// Newest removal first
+	entries := ms.trashList()
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	var matches []storage.TrashEntry
+	for _, entry := range entries {
+		if query != "" && strings.HasPrefix(entry.ID, query) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	// Match by name
+	files := make([]storage.FileMetadata, len(entries))
+	for i := range entries {
+		files[i] = entries[i].File
+	}
+	for _, i := range matchFileNames(files, query) {
+		matches = append(matches, entries[i])
+	}
+	return matches
+}
@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+func TestMatchTrash(t *testing.T) {
+	v, key := newTestVault(t, "directory")
+	ms := newMetaStore(v, key)
+	removed := time.Now()
+	for _, entry := range []storage.TrashEntry{
+		{ID: "aa11", DeletedAt: removed, File: storage.FileMetadata{OriginalName: "notes.txt"}},
+		{ID: "aa22", DeletedAt: removed.Add(time.Minute), File: storage.FileMetadata{OriginalName: "notes.txt"}},
+		{ID: "bb33", DeletedAt: removed.Add(2 * time.Minute), File: storage.FileMetadata{OriginalName: "old-notes.txt"}},
+	} {
+		ms.putTrash(entry)
+	}
+
+	tests := []struct {
+		query     string
+		wantEntry string // trashEntry result, "" for none
+		want      []string
+	}{
+		{"aa11", "aa11", []string{"aa11"}},
+		{"notes.txt", "aa22", []string{"aa22"}},
+		{"aa", "", []string{"aa22", "aa11"}},
+		{"b", "", []string{"bb33"}},
+		{"NOTES", "", []string{"bb33", "aa22", "aa11"}},
+		{"old-notes", "", []string{"bb33"}},
+		{"missing", "", nil},
+	}
+	for _, tt := range tests {
+		gotEntry := ""
+		if entry := ms.trashEntry(tt.query); entry != nil {
+			gotEntry = entry.ID
+		}
+		if gotEntry != tt.wantEntry {
+			t.Errorf("trashEntry(%q) = %q, want %q", tt.query, gotEntry, tt.wantEntry)
+		}
+
+		var got []string
+		for _, entry := range ms.matchTrash(tt.query) {
+			got = append(got, entry.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("matchTrash(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("matchTrash(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
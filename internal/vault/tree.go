@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// TreePlan describes what adding a directory tree would do, without doing it
+type TreePlan struct {
+	Files       []PlannedFile // files that would be encrypted and securely deleted
+	RemovedDirs []string      // directories that would be left empty and removed
+}
+
+// PlannedFile is a file a TreePlan would encrypt
+type PlannedFile struct {
+	Path string // path on disk
+	Name string // name it would be stored under in the vault
+}
+
+// AddDirectory encrypts and adds every file under a directory, recursively
+// Files are stored under their path relative to the directory's parent, so
+// adding "certs" stores "certs/prod/server.pem". Adding the vault directory
+// itself stores paths relative to it. Only files selected by the filter are
+// added. Returns the number of files added.
+func (s *Session) AddDirectory(dirPath string, filter *storage.FileFilter) (int, error) {
+	if err := checkDirectory(dirPath); err != nil {
+		return 0, err
+	}
+
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return 0, err
+	}
+
+	// Read and decrypt existing metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	files, err := storage.ListDirectoryTree(dirPath, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix, keepDir := v.treeLayout(dirPath)
+	return v.addTree(ms, dirPath, prefix, keepDir, files)
+}
+
+// PlanAddDirectory reports what AddDirectory would encrypt and delete
+func (v *Vault) PlanAddDirectory(dirPath string, filter *storage.FileFilter) (*TreePlan, error) {
+	if err := checkDirectory(dirPath); err != nil {
+		return nil, err
+	}
+
+	prefix, keepDir := v.treeLayout(dirPath)
+	return planTree(dirPath, prefix, keepDir, filter)
+}
+
+// PlanInitialize reports what Initialize would encrypt and delete
+func (v *Vault) PlanInitialize(filter *storage.FileFilter) (*TreePlan, error) {
+	contentDir := v.contentDir()
+	return planTree(contentDir, "", contentDir, filter)
+}
+
+// planTree lists the files under baseDir that addTree would be given
+func planTree(baseDir, prefix, keepDir string, filter *storage.FileFilter) (*TreePlan, error) {
+	files, err := storage.ListDirectoryTree(baseDir, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &TreePlan{RemovedDirs: storage.EmptiedDirectories(files, keepDir)}
+	for _, filePath := range files {
+		name, err := treeName(baseDir, prefix, filePath)
+		if err != nil {
+			return nil, err
+		}
+		plan.Files = append(plan.Files, PlannedFile{Path: filePath, Name: name})
+	}
+	return plan, nil
+}
+
+// checkDirectory returns an error unless the path is a directory
+func checkDirectory(dirPath string) error {
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", dirPath)
+	}
+	return nil
+}
+
+// treeLayout returns the name prefix for files added from dirPath, and the
+// directory above which emptied directories are kept
+// The added directory itself is removed once empty, unless it is the vault.
+func (v *Vault) treeLayout(dirPath string) (prefix, keepDir string) {
+	if filepath.Clean(dirPath) == filepath.Clean(v.rootPath) {
+		return "", dirPath
+	}
+	return filepath.Base(dirPath), filepath.Dir(dirPath)
+}
+
+// addTree adds files found under baseDir, naming each by its path relative to
+// baseDir below prefix, and securely deletes the originals
+// Directories left empty are removed, up to but not including keepDir.
+// Returns the number of files added.
+func (v *Vault) addTree(ms *metaStore, baseDir, prefix, keepDir string, files []string) (int, error) {
+	w, err := v.newWiper(ms)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var deleted []string
+	defer func() {
+		storage.RemoveEmptyParents(deleted, keepDir)
+	}()
+
+	for i, filePath := range files {
+		name, err := treeName(baseDir, prefix, filePath)
+		if err != nil {
+			return count, err
+		}
+
+		if v.onProgress != nil {
+			v.onProgress(i+1, len(files), name)
+		}
+
+		if _, err := v.addFileInternal(ms, filePath, name); err != nil {
+			return count, fmt.Errorf("failed to encrypt %s: %w", filePath, err)
+		}
+		count++
+
+		// Delete original file immediately after successful encryption to save disk space
+		if err := w.delete(filePath); err != nil {
+			// Log warning but don't fail - file is already encrypted
+			fmt.Fprintf(os.Stderr, "warning: failed to delete %s: %v\n", filePath, err)
+			continue
+		}
+		deleted = append(deleted, filePath)
+	}
+
+	return count, nil
+}
+
+// treeName returns the vault name of a file found under baseDir
+func treeName(baseDir, prefix, filePath string) (string, error) {
+	rel, err := filepath.Rel(baseDir, filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", filePath, err)
+	}
+	return path.Join(prefix, filepath.ToSlash(rel)), nil
+}
@@ -0,0 +1,105 @@
+package vault
+
+import "fmt"
+
+// UpdateResult describes the update of one vaulted file from a local copy
+type UpdateResult struct {
+	Name    string // name in the vault
+	Path    string // local file read
+	Version int    // version now current
+	Changed bool   // false if the local file matched the vault and nothing was stored
+	NotFile bool   // skipped because something other than a file is at Path
+}
+
+// UpdateFile replaces the contents of a vaulted file with a local file
+// The vaulted file is found as in VerifyFile and must already exist. The new
+// contents become the next version in a single metadata commit, so the vault
+// always holds a complete copy, and AddedAt is kept. The local file is left
+// in place.
+func (s *Session) UpdateFile(localPath, name string) (*UpdateResult, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	fileMeta := ms.findLocal(localPath, name)
+	if fileMeta == nil {
+		return nil, ErrFileNotFound
+	}
+
+	result := &UpdateResult{Name: fileMeta.OriginalName, Path: localPath, Version: currentVersion(fileMeta).Version}
+
+	match, _, err := v.compareLocal(masterKey, fileMeta, localPath)
+	if err != nil {
+		return nil, err
+	}
+	if match {
+		return result, nil
+	}
+
+	version, err := v.addFileInternal(ms, localPath, fileMeta.OriginalName)
+	if err != nil {
+		return nil, err
+	}
+	result.Version = version
+	result.Changed = true
+	return result, nil
+}
+
+// UpdateAll pushes every modified file in a directory back into the vault
+// Files are matched and classified as in Status; only modified files are
+// updated. Returns the files updated, followed by those skipped because
+// something other than a file is at their path.
+func (s *Session) UpdateAll(dir string) ([]UpdateResult, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := v.statusInternal(masterKey, ms, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var modified, notFiles []FileStatus
+	for _, st := range statuses {
+		switch st.State {
+		case StateModified:
+			modified = append(modified, st)
+		case StateNotFile:
+			notFiles = append(notFiles, st)
+		}
+	}
+
+	var results []UpdateResult
+	for i, st := range modified {
+		if v.onProgress != nil {
+			v.onProgress(i+1, len(modified), st.Name)
+		}
+
+		// Status has already found the contents to differ
+		version, err := v.addFileInternal(ms, st.Path, st.Name)
+		if err != nil {
+			return results, fmt.Errorf("failed to update %s: %w", st.Name, err)
+		}
+		results = append(results, UpdateResult{Name: st.Name, Path: st.Path, Version: version, Changed: true})
+	}
+
+	for _, st := range notFiles {
+		fileMeta := ms.fileByName(st.Name)
+		results = append(results, UpdateResult{Name: st.Name, Path: st.Path, Version: currentVersion(fileMeta).Version, NotFile: true})
+	}
+	return results, nil
+}
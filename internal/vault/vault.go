@@ -1,7 +1,8 @@
 package vault
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -13,15 +14,37 @@ import (
 	"github.com/Zayan-Mohamed/vaultix/internal/storage"
 )
 
+// metadataVersion is the metadata format written for new vaults
+// Version 2 added content-defined chunks (version 1 files remain readable);
+// version 3 stores metadata as an append-only log instead of a single blob
+const metadataVersion = 3
+
 var (
 	ErrFileAlreadyExists = errors.New("file already exists in vault")
 	ErrFileNotFound      = errors.New("file not found in vault")
+	ErrHashMismatch      = errors.New("decrypted content does not match the stored hash")
+	ErrSymlink           = errors.New("file is a symbolic link")
 )
 
+// ExtractOptions selects which stored contents extract reads
+type ExtractOptions struct {
+	Snapshot string // snapshot ID or unique prefix; empty means the current files
+	Version  int    // file version to extract; 0 means the latest
+	// Restore selects which file attributes are restored
+	Restore storage.RestoreOptions
+	// OnConflict selects what happens when an output file already exists;
+	// empty means overwrite
+	OnConflict ConflictPolicy
+	// Ask decides each conflict under ConflictAsk, returning skip, overwrite,
+	// rename or newer. Without it, conflicts are skipped.
+	Ask func(outputPath string, stored *storage.FileMetadata, existing os.FileInfo) ConflictPolicy
+}
+
 // Vault represents a secure vault instance
 type Vault struct {
 	rootPath   string
 	onProgress func(current, total int, message string)
+	packs      *storage.Packs // pack index, open while unlocked
 }
 
 // New creates a new vault instance at the given path
@@ -34,11 +57,23 @@ func (v *Vault) SetProgressCallback(callback func(current, total int, message st
 	v.onProgress = callback
 }
 
-// Initialize creates a new vault with the given password and encrypts all files in the directory
+// contentDir returns the directory whose files the vault encrypts on init
+// A container vault encrypts the files in the directory that holds it.
+func (v *Vault) contentDir() string {
+	if storage.IsContainer(v.rootPath) {
+		return filepath.Dir(v.rootPath)
+	}
+	return v.rootPath
+}
+
+// Initialize creates a new vault with the given password and encrypts the files
+// in the directory selected by the filter (nil selects all)
 // Returns the recovery key that should be saved by the user
-func (v *Vault) Initialize(password string) ([]byte, error) {
+func (v *Vault) Initialize(password string, filter *storage.FileFilter) ([]byte, error) {
 	// Get list of files to encrypt before creating vault structure
-	filesToEncrypt, err := storage.ListDirectoryFiles(v.rootPath)
+	contentDir := v.contentDir()
+	// Other vaults in the tree are skipped, so they are never encrypted and deleted
+	filesToEncrypt, err := storage.ListDirectoryTree(contentDir, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
@@ -91,612 +126,510 @@ func (v *Vault) Initialize(password string) ([]byte, error) {
 	}
 
 	// Encrypt the initial empty metadata with master key
-	meta := &storage.VaultMetadata{
-		Version: 1,
-		Files:   []storage.FileMetadata{},
-	}
-	if err := v.writeMetadata(masterKey, meta); err != nil {
+	ms := newMetaStore(v, masterKey)
+	if err := ms.compact(); err != nil {
 		return nil, fmt.Errorf("failed to write initial metadata: %w", err)
 	}
-
-	// Encrypt all files in the directory
-	totalFiles := len(filesToEncrypt)
-	if totalFiles > 0 {
-		for i, filePath := range filesToEncrypt {
-			if v.onProgress != nil {
-				v.onProgress(i+1, totalFiles, filepath.Base(filePath))
-			}
-
-			if err := v.addFileInternal(filePath, masterKey); err != nil {
-				return nil, fmt.Errorf("failed to encrypt %s: %w", filePath, err)
-			}
-
-			// Delete original file immediately after successful encryption to save disk space
-			if err := storage.SecureDelete(filePath); err != nil {
-				// Log warning but don't fail - file is already encrypted
-				fmt.Fprintf(os.Stderr, "warning: failed to delete %s: %v\n", filePath, err)
-			}
-		}
-	}
-
-	return recoveryKey, nil
-}
-
-// unlockWithPassword decrypts the master key using the password
-func (v *Vault) unlockWithPassword(password string) ([]byte, error) {
-	// Read salt
-	salt, err := storage.ReadSalt(v.rootPath)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read encrypted master key
-	encryptedMasterKey, err := storage.ReadMasterKey(v.rootPath)
-	if err != nil {
-		return nil, err
-	}
-
-	// Decrypt master key
-	masterKey, err := crypto.DecryptMasterKey(encryptedMasterKey, password, salt)
-	if err != nil {
-		return nil, err
-	}
-
-	return masterKey, nil
-}
-
-// UnlockWithRecoveryKey decrypts the master key using the recovery key
-func (v *Vault) UnlockWithRecoveryKey(recoveryKey []byte) ([]byte, error) {
-	// Read encrypted master key (for recovery)
-	encryptedMasterKeyForRecovery, err := storage.ReadRecoveryKey(v.rootPath)
-	if err != nil {
+	if err := v.openPacks(masterKey); err != nil {
 		return nil, err
 	}
+	defer v.closePacks()
 
-	// Decrypt master key
-	masterKey, err := crypto.DecryptMasterKeyWithRecoveryKey(encryptedMasterKeyForRecovery, recoveryKey)
-	if err != nil {
+	// Encrypt all files in the directory tree
+	if _, err := v.addTree(ms, contentDir, "", contentDir, filesToEncrypt); err != nil {
 		return nil, err
 	}
 
-	return masterKey, nil
-}
-
-// ListFilesWithMasterKey lists files using the master key directly (for recovery)
-func (v *Vault) ListFilesWithMasterKey(masterKey []byte) ([]storage.FileMetadata, error) {
-	meta, err := v.readMetadata(masterKey)
-	if err != nil {
-		return nil, err
-	}
-	return meta.Files, nil
+	return recoveryKey, nil
 }
 
-// ExtractFileWithMasterKey extracts a file using the master key directly (for recovery)
-func (v *Vault) ExtractFileWithMasterKey(masterKey []byte, fileName, destPath string) (string, error) {
-	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
-	if err != nil {
-		return "", err
-	}
-
-	// Find the file with fuzzy matching
-	fileMeta := findFileByName(meta.Files, fileName)
-	if fileMeta == nil {
-		return "", ErrFileNotFound
-	}
-
-	// Read encrypted object
-	encryptedData, err := storage.ReadObject(v.rootPath, fileMeta.ID)
+// KeyFingerprint identifies the vault's encrypted master key and salt, which
+// change if the vault is recreated or its password is changed
+func (v *Vault) KeyFingerprint() (string, error) {
+	salt, err := storage.ReadSalt(v.rootPath)
 	if err != nil {
 		return "", err
 	}
-
-	// Decrypt with master key
-	plaintext, err := crypto.Decrypt(encryptedData, masterKey)
+	encryptedMasterKey, err := storage.ReadMasterKey(v.rootPath)
 	if err != nil {
 		return "", err
 	}
 
-	// Determine output path
-	outputPath := destPath
-	if destPath == "" || destPath == "." {
-		outputPath = fileMeta.OriginalName
-	}
-
-	// Write decrypted file
-	if err := storage.WritePlaintextFile(outputPath, plaintext, fileMeta.ModTime); err != nil {
-		return "", err
-	}
-
-	return fileMeta.OriginalName, nil
+	sum := sha256.Sum256(append(salt, encryptedMasterKey...))
+	return hex.EncodeToString(sum[:]), nil
 }
 
-// ExtractAllFilesWithMasterKey extracts all files using the master key directly (for recovery)
-func (v *Vault) ExtractAllFilesWithMasterKey(masterKey []byte, destDir string) (int, error) {
-	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+// AddFile encrypts and adds a file to the vault
+// If a file with the same name is already stored, its contents become a new
+// version of that file. Returns the version number stored.
+func (s *Session) AddFile(filePath string) (int, error) {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
 		return 0, err
 	}
 
-	if len(meta.Files) == 0 {
-		return 0, nil
-	}
-
-	// Extract each file
-	count := 0
-	totalFiles := len(meta.Files)
-	for i, fileMeta := range meta.Files {
-		if v.onProgress != nil {
-			v.onProgress(i+1, totalFiles, fileMeta.OriginalName)
-		}
-
-		// Read encrypted object
-		encryptedData, err := storage.ReadObject(v.rootPath, fileMeta.ID)
-		if err != nil {
-			return count, fmt.Errorf("failed to read %s: %w", fileMeta.OriginalName, err)
-		}
-
-		// Decrypt with master key
-		plaintext, err := crypto.Decrypt(encryptedData, masterKey)
-		if err != nil {
-			return count, fmt.Errorf("failed to decrypt %s: %w", fileMeta.OriginalName, err)
-		}
-
-		// Determine output path
-		outputPath := fileMeta.OriginalName
-		if destDir != "" && destDir != "." {
-			outputPath = filepath.Join(destDir, fileMeta.OriginalName)
-		}
-
-		// Write decrypted file
-		if err := storage.WritePlaintextFile(outputPath, plaintext, fileMeta.ModTime); err != nil {
-			return count, fmt.Errorf("failed to write %s: %w", fileMeta.OriginalName, err)
-		}
-
-		count++
+	// Read and decrypt existing metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read metadata: %w", err)
 	}
 
-	return count, nil
-}
-
-// AddFile encrypts and adds a file to the vault
-func (v *Vault) AddFile(password, filePath string) error {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+	w, err := v.newWiper(ms)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Add the file using internal helper
-	if err := v.addFileInternal(filePath, masterKey); err != nil {
-		return err
+	version, err := v.addFileInternal(ms, filePath, filepath.Base(filePath))
+	if err != nil {
+		return 0, err
 	}
 
 	// Securely delete the original file
-	if err := storage.SecureDelete(filePath); err != nil {
+	if err := w.delete(filePath); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to securely delete original file: %v\n", err)
 		// Continue anyway - the file was encrypted successfully
 	}
 
-	return nil
+	return version, nil
 }
 
 // addFileInternal is the internal implementation for adding files
-func (v *Vault) addFileInternal(filePath string, masterKey []byte) error {
+// The file is stored under name, a slash-separated path relative to the vault
+// Returns the version number stored
+func (v *Vault) addFileInternal(ms *metaStore, filePath, name string) (int, error) {
 	// Read the file to be added
 	data, info, err := storage.ReadPlaintextFile(filePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	// Read and decrypt existing metadata
-	meta, err := v.readMetadata(masterKey)
+	attrs, err := storage.ReadFileAttributes(filePath, info)
 	if err != nil {
-		return fmt.Errorf("failed to read metadata: %w", err)
+		return 0, err
 	}
 
-	// Check if file already exists
-	fileName := filepath.Base(filePath)
-	for _, f := range meta.Files {
-		if f.OriginalName == fileName {
-			return ErrFileAlreadyExists
-		}
-	}
+	return v.storeContents(ms, name, data, info.ModTime(), attrs)
+}
 
-	// Generate unique object ID
-	objectID := storage.GenerateObjectID(fileName)
+// storeContents stores data under name, as a new file or as the next version
+// of an existing one, and commits the metadata
+// Returns the version number stored
+func (v *Vault) storeContents(ms *metaStore, name string, data []byte, modTime time.Time, attrs storage.FileAttributes) (int, error) {
+	// Refuse names that could not be extracted safely
+	if err := storage.ValidateFileName(name); err != nil {
+		return 0, err
+	}
 
-	// Encrypt file data with master key
-	encryptedData, err := crypto.Encrypt(data, masterKey)
+	// Split into chunks, encrypting and writing only chunks not already stored
+	chunkIDs, written, err := v.storeFileData(ms, data)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt file: %w", err)
+		return 0, fmt.Errorf("failed to store file: %w", err)
 	}
 
-	// Write encrypted object
-	if err := storage.WriteObject(v.rootPath, objectID, encryptedData); err != nil {
-		return err
+	now := time.Now()
+
+	var fileMeta storage.FileMetadata
+	if existing := ms.fileByName(name); existing != nil {
+		// Keep the current contents as an earlier version
+		fileMeta = *existing
+		fileMeta.Versions = append(append([]storage.FileVersion(nil), existing.Versions...), currentVersion(existing))
+		fileMeta.Version = currentVersion(existing).Version + 1
+	} else {
+		fileMeta = storage.FileMetadata{
+			ID:           storage.GenerateObjectID(name),
+			OriginalName: name,
+			AddedAt:      now,
+			Version:      1,
+		}
 	}
+	fileMeta.Size = int64(len(data))
+	fileMeta.ModTime = modTime
+	fileMeta.FileAttributes = attrs
+	fileMeta.UpdatedAt = now
+	fileMeta.Chunks = chunkIDs
+	fileMeta.SHA256 = storage.ContentHash(data)
 
 	// Add to metadata
-	fileMeta := storage.FileMetadata{
-		ID:           objectID,
-		OriginalName: fileName,
-		Size:         info.Size(),
-		ModTime:      info.ModTime(),
-		AddedAt:      time.Now(),
-	}
-	meta.Files = append(meta.Files, fileMeta)
-
-	// Encrypt and write updated metadata
-	if err := v.writeMetadata(masterKey, meta); err != nil {
-		// Try to clean up the object file
-		storage.DeleteObject(v.rootPath, objectID)
-		return fmt.Errorf("failed to update metadata: %w", err)
+	ms.putFile(fileMeta)
+
+	// Encrypt and append the metadata changes
+	if err := ms.commit(); err != nil {
+		// Try to clean up the chunks this file introduced
+		v.deleteObjects(written)
+		return 0, fmt.Errorf("failed to update metadata: %w", err)
 	}
 
-	return nil
+	return fileMeta.Version, nil
 }
 
 // ListFiles returns the list of files in the vault
-func (v *Vault) ListFiles(password string) ([]storage.FileMetadata, error) {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+func (s *Session) ListFiles() ([]storage.FileMetadata, error) {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
 		return nil, err
 	}
 
 	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
 		return nil, err
 	}
 
-	return meta.Files, nil
+	return ms.fileList(), nil
 }
 
 // ExtractFile decrypts and extracts a file from the vault
-// Returns the actual filename that was matched (for fuzzy matching)
-func (v *Vault) ExtractFile(password, fileName, destPath string) (string, error) {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+// The result holds the actual filename that was matched (for fuzzy matching)
+// as the file extracted or skipped.
+func (s *Session) ExtractFile(fileName, destPath string, opts ExtractOptions) (*ExtractResult, error) {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	return v.extractFileInternal(masterKey, fileName, destPath, opts)
+}
+
+// ExtractAllFiles decrypts and extracts all files from the vault
+// On error, the result describes the files handled so far.
+func (s *Session) ExtractAllFiles(destDir string, opts ExtractOptions) (*ExtractResult, error) {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
-		return "", err
+		return &ExtractResult{}, err
 	}
 
-	// Find the file with fuzzy matching
-	fileMeta := findFileByName(meta.Files, fileName)
-
-	if fileMeta == nil {
-		return "", ErrFileNotFound
-	}
+	return v.extractAllInternal(masterKey, destDir, opts)
+}
 
-	// Read encrypted object
-	encryptedData, err := storage.ReadObject(v.rootPath, fileMeta.ID)
+// extractFileInternal is the internal implementation for extracting a single file
+func (v *Vault) extractFileInternal(masterKey []byte, fileName, destPath string, opts ExtractOptions) (*ExtractResult, error) {
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Decrypt with master key
-	plaintext, err := crypto.Decrypt(encryptedData, masterKey)
+	fileMeta, err := v.lookupFile(ms, fileName, opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Determine output path - use original filename, not the query
+	// A path given by the caller is used as is; a stored name must stay
+	// beneath the current directory
 	outputPath := destPath
 	if destPath == "" || destPath == "." {
-		outputPath = fileMeta.OriginalName
+		outputPath, err = storage.SafeJoin("", fileMeta.OriginalName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Write decrypted file
-	if err := storage.WritePlaintextFile(outputPath, plaintext, fileMeta.ModTime); err != nil {
-		return "", err
+	result := &ExtractResult{}
+	if _, err := v.writeExtracted(masterKey, fileMeta, outputPath, opts, result); err != nil {
+		return nil, err
 	}
-
-	return fileMeta.OriginalName, nil
+	return result, nil
 }
 
-// ExtractAllFiles decrypts and extracts all files from the vault
-func (v *Vault) ExtractAllFiles(password, destDir string) (int, error) {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
-	if err != nil {
-		return 0, err
+// lookupFile finds a file to extract, with fuzzy matching, in the current
+// files or the snapshot selected by opts, and selects the requested version
+func (v *Vault) lookupFile(ms *metaStore, fileName string, opts ExtractOptions) (*storage.FileMetadata, error) {
+	var fileMeta *storage.FileMetadata
+	if opts.Snapshot == "" {
+		fileMeta = ms.findFile(fileName)
+	} else {
+		files, err := v.extractSource(ms, opts)
+		if err != nil {
+			return nil, err
+		}
+		fileMeta = findFileByName(files, fileName)
+	}
+	if fileMeta == nil {
+		return nil, ErrFileNotFound
 	}
 
+	return selectVersion(fileMeta, opts.Version)
+}
+
+// extractAllInternal is the internal implementation for extracting all files
+// On error, the result describes the files handled so far.
+func (v *Vault) extractAllInternal(masterKey []byte, destDir string, opts ExtractOptions) (*ExtractResult, error) {
+	result := &ExtractResult{}
+
 	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 
-	if len(meta.Files) == 0 {
-		return 0, nil
+	files, err := v.extractSource(ms, opts)
+	if err != nil {
+		return result, err
 	}
 
 	// Extract each file
-	count := 0
-	totalFiles := len(meta.Files)
-	for i, fileMeta := range meta.Files {
+	totalFiles := len(files)
+	for i := range files {
+		fileMeta := &files[i]
 		if v.onProgress != nil {
 			v.onProgress(i+1, totalFiles, fileMeta.OriginalName)
 		}
 
-		// Read encrypted object
-		encryptedData, err := storage.ReadObject(v.rootPath, fileMeta.ID)
+		// Determine output path, confined beneath the destination directory
+		outputPath, err := storage.SafeJoin(destDir, fileMeta.OriginalName)
 		if err != nil {
-			return count, fmt.Errorf("failed to read %s: %w", fileMeta.OriginalName, err)
+			return result, err
 		}
 
-		// Decrypt with master key
-		plaintext, err := crypto.Decrypt(encryptedData, masterKey)
-		if err != nil {
-			return count, fmt.Errorf("failed to decrypt %s: %w", fileMeta.OriginalName, err)
+		if _, err := v.writeExtracted(masterKey, fileMeta, outputPath, opts, result); err != nil {
+			return result, err
 		}
+	}
 
-		// Determine output path
-		outputPath := fileMeta.OriginalName
-		if destDir != "" && destDir != "." {
-			outputPath = filepath.Join(destDir, fileMeta.OriginalName)
-		}
+	return result, nil
+}
 
-		// Write decrypted file
-		if err := storage.WritePlaintextFile(outputPath, plaintext, fileMeta.ModTime); err != nil {
-			return count, fmt.Errorf("failed to write %s: %w", fileMeta.OriginalName, err)
-		}
+// DropFile extracts the file with exactly this name and then removes it from
+// the vault
+// The file is moved to the trash unless permanent is set. A file skipped
+// because of a conflict stays in the vault. Drop always works on the current
+// files, so opts.Snapshot and opts.Version are ignored.
+func (s *Session) DropFile(fileName, destPath string, opts ExtractOptions, permanent bool) (*ExtractResult, error) {
+	opts.Snapshot, opts.Version = "", 0
 
-		count++
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
 	}
 
-	return count, nil
-}
+	// Only an exact name may be dropped; extraction alone would match fuzzily
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if ms.fileByName(fileName) == nil {
+		return nil, ErrFileNotFound
+	}
 
-// DropFile extracts a file and then removes it from the vault
-func (v *Vault) DropFile(password, fileName, destPath string) (string, error) {
 	// First extract the file
-	actualFileName, err := v.ExtractFile(password, fileName, destPath)
+	result, err := v.extractFileInternal(masterKey, fileName, destPath, opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Then remove it from the vault
-	if err := v.RemoveFile(password, actualFileName); err != nil {
-		return "", fmt.Errorf("extracted but failed to remove from vault: %w", err)
+	ms, err = v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.expireTrash(ms); err != nil {
+		return nil, err
+	}
+	for _, name := range result.Extracted {
+		fileMeta := ms.fileByName(name)
+		if fileMeta == nil {
+			return nil, fmt.Errorf("extracted but failed to remove from vault: %w", ErrFileNotFound)
+		}
+		if err := v.removeFileInternal(ms, fileMeta, permanent); err != nil {
+			return nil, fmt.Errorf("extracted but failed to remove from vault: %w", err)
+		}
 	}
 
-	return actualFileName, nil
+	return result, nil
 }
 
 // DropAllFiles extracts all files and then removes them from the vault
-func (v *Vault) DropAllFiles(password, destDir string) (int, error) {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+// The files are moved to the trash unless permanent is set; files skipped
+// because of a conflict stay in the vault. Snapshot and Version in opts are
+// ignored. On error, the result describes the files handled so far.
+func (s *Session) DropAllFiles(destDir string, opts ExtractOptions, permanent bool) (*ExtractResult, error) {
+	result := &ExtractResult{}
+
+	v, masterKey, err := s.unlocked()
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 
 	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 
-	if len(meta.Files) == 0 {
-		return 0, nil
+	if err := v.expireTrash(ms); err != nil {
+		return result, err
 	}
 
 	// Extract and remove each file
-	count := 0
-	totalFiles := len(meta.Files)
-	newFiles := make([]storage.FileMetadata, 0, len(meta.Files))
+	files := ms.fileList()
+	totalFiles := len(files)
 
-	for i, fileMeta := range meta.Files {
+	for i := range files {
+		fileMeta := &files[i]
 		if v.onProgress != nil {
 			v.onProgress(i+1, totalFiles, fileMeta.OriginalName)
 		}
 
-		// Read encrypted object
-		encryptedData, err := storage.ReadObject(v.rootPath, fileMeta.ID)
+		// Determine output path, confined beneath the destination directory
+		outputPath, err := storage.SafeJoin(destDir, fileMeta.OriginalName)
 		if err != nil {
-			newFiles = append(newFiles, meta.Files[count:]...)
-			meta.Files = newFiles
-			_ = v.writeMetadata(masterKey, meta)
-			return count, fmt.Errorf("failed to read %s: %w", fileMeta.OriginalName, err)
+			return result, err
 		}
 
-		// Decrypt with master key
-		plaintext, err := crypto.Decrypt(encryptedData, masterKey)
+		written, err := v.writeExtracted(masterKey, fileMeta, outputPath, opts, result)
 		if err != nil {
-			newFiles = append(newFiles, meta.Files[count:]...)
-			meta.Files = newFiles
-			_ = v.writeMetadata(masterKey, meta)
-			return count, fmt.Errorf("failed to decrypt %s: %w", fileMeta.OriginalName, err)
-		}
-
-		// Determine output path
-		outputPath := fileMeta.OriginalName
-		if destDir != "" && destDir != "." {
-			outputPath = filepath.Join(destDir, fileMeta.OriginalName)
+			return result, err
 		}
-
-		// Write decrypted file
-		if err := storage.WritePlaintextFile(outputPath, plaintext, fileMeta.ModTime); err != nil {
-			newFiles = append(newFiles, meta.Files[count:]...)
-			meta.Files = newFiles
-			_ = v.writeMetadata(masterKey, meta)
-			return count, fmt.Errorf("failed to write %s: %w", fileMeta.OriginalName, err)
+		if !written {
+			continue
 		}
 
-		// Delete the encrypted object immediately to save disk space
-		if err := storage.DeleteObject(v.rootPath, fileMeta.ID); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to delete object %s: %v\n", fileMeta.ID, err)
+		// Remove the file immediately so an interruption leaves no file both
+		// extracted and still in the vault
+		if err := v.removeFileInternal(ms, fileMeta, permanent); err != nil {
+			return result, fmt.Errorf("extracted %s but failed to remove it from vault: %w", fileMeta.OriginalName, err)
 		}
-
-		count++
-	}
-
-	// Clear metadata
-	meta.Files = []storage.FileMetadata{}
-	if err := v.writeMetadata(masterKey, meta); err != nil {
-		return count, fmt.Errorf("extracted %d files but failed to update metadata: %w", count, err)
 	}
 
-	return count, nil
+	return result, nil
 }
 
 // ClearVault removes all files from the vault without extracting them
-func (v *Vault) ClearVault(password string) error {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+// The files are moved to the trash unless permanent is set
+func (s *Session) ClearVault(permanent bool) error {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
 		return err
 	}
 
 	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
 		return err
 	}
 
-	// Delete all objects
-	for _, f := range meta.Files {
-		if err := storage.DeleteObject(v.rootPath, f.ID); err != nil {
-			return fmt.Errorf("failed to delete %s: %w", f.OriginalName, err)
-		}
+	if err := v.expireTrash(ms); err != nil {
+		return err
 	}
 
-	// Clear metadata
-	meta.Files = []storage.FileMetadata{}
-	if err := v.writeMetadata(masterKey, meta); err != nil {
+	// Remove all files in one metadata update
+	var unreferenced []string
+	for _, f := range ms.fileList() {
+		unreferenced = append(unreferenced, ms.discardFile(&f, permanent)...)
+	}
+
+	if err := ms.commit(); err != nil {
 		return err
 	}
 
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
 	return nil
 }
 
-// RemoveFile removes a file from the vault
-func (v *Vault) RemoveFile(password, fileName string) error {
-	// Unlock vault with password
-	masterKey, err := v.unlockWithPassword(password)
+// RemoveFile removes the file with exactly this name from the vault
+// The file is moved to the trash unless permanent is set
+func (s *Session) RemoveFile(fileName string, permanent bool) error {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
 		return err
 	}
 
 	// Read and decrypt metadata
-	meta, err := v.readMetadata(masterKey)
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
 		return err
 	}
 
-	// Find and remove the file with fuzzy matching
-	var objectID string
-	found := false
-	newFiles := make([]storage.FileMetadata, 0, len(meta.Files))
-	for _, f := range meta.Files {
-		if matchesFileName(f.OriginalName, fileName) {
-			objectID = f.ID
-			found = true
-		} else {
-			newFiles = append(newFiles, f)
-		}
-	}
-
-	if !found {
-		return ErrFileNotFound
-	}
-
-	// Delete the object file
-	if err := storage.DeleteObject(v.rootPath, objectID); err != nil {
+	if err := v.expireTrash(ms); err != nil {
 		return err
 	}
 
-	// Update metadata
-	meta.Files = newFiles
-	if err := v.writeMetadata(masterKey, meta); err != nil {
-		return fmt.Errorf("failed to update metadata: %w", err)
+	fileMeta := ms.fileByName(fileName)
+	if fileMeta == nil {
+		return ErrFileNotFound
 	}
 
-	return nil
+	return v.removeFileInternal(ms, fileMeta, permanent)
 }
 
-// readMetadata reads and decrypts the vault metadata
-func (v *Vault) readMetadata(key []byte) (*storage.VaultMetadata, error) {
-	encryptedMeta, err := storage.ReadMetadata(v.rootPath)
+// FindFiles returns the names of the files a partial or differently cased
+// name could mean, for resolving it before an operation that needs the exact
+// name; an exact name gives only itself
+func (s *Session) FindFiles(query string) ([]string, error) {
+	v, masterKey, err := s.unlocked()
 	if err != nil {
 		return nil, err
 	}
 
-	// Decrypt metadata
-	plainMeta, err := crypto.Decrypt(encryptedMeta, key)
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
 	if err != nil {
 		return nil, err
 	}
 
-	// Unmarshal JSON
-	var meta storage.VaultMetadata
-	if err := json.Unmarshal(plainMeta, &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	files := ms.fileList()
+	var names []string
+	for _, i := range matchFileNames(files, query) {
+		names = append(names, files[i].OriginalName)
 	}
+	return names, nil
+}
 
-	return &meta, nil
+// removeFileInternal removes a file entry, moving it to the trash or deleting
+// data no other file references
+func (v *Vault) removeFileInternal(ms *metaStore, fileMeta *storage.FileMetadata, permanent bool) error {
+	unreferenced := ms.discardFile(fileMeta, permanent)
+
+	if err := ms.commit(); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
+	return nil
 }
 
 // findFileByName performs fuzzy matching to find a file
-// Tries: exact match, case-insensitive, contains, case-insensitive contains
+// Returns the first of the files matchFileNames gives, or nil.
 func findFileByName(files []storage.FileMetadata, query string) *storage.FileMetadata {
+	matches := matchFileNames(files, query)
+	if len(matches) == 0 {
+		return nil
+	}
+	return &files[matches[0]]
+}
+
+// matchFileNames returns the indexes of the files a query could mean
+// Tries: exact match, case-insensitive, case-insensitive contains; the first
+// pass with any match gives the result.
+func matchFileNames(files []storage.FileMetadata, query string) []int {
 	lowerQuery := strings.ToLower(query)
 
 	// First pass: exact match
 	for i := range files {
 		if files[i].OriginalName == query {
-			return &files[i]
+			return []int{i}
 		}
 	}
 
 	// Second pass: case-insensitive exact match
+	var matches []int
 	for i := range files {
 		if strings.ToLower(files[i].OriginalName) == lowerQuery {
-			return &files[i]
+			matches = append(matches, i)
 		}
 	}
+	if len(matches) > 0 {
+		return matches
+	}
 
 	// Third pass: contains (case-insensitive)
 	for i := range files {
 		if strings.Contains(strings.ToLower(files[i].OriginalName), lowerQuery) {
-			return &files[i]
+			matches = append(matches, i)
 		}
 	}
-
-	return nil
-}
-
-// matchesFileName checks if a filename matches the query (fuzzy matching)
-func matchesFileName(filename, query string) bool {
-	return strings.EqualFold(filename, query) ||
-		strings.Contains(strings.ToLower(filename), strings.ToLower(query))
-}
-
-// writeMetadata encrypts and writes the vault metadata
-func (v *Vault) writeMetadata(key []byte, meta *storage.VaultMetadata) error {
-	// Marshal to JSON
-	plainMeta, err := json.Marshal(meta)
-	if err != nil {
-		return fmt.Errorf("failed to serialize metadata: %w", err)
-	}
-
-	// Encrypt metadata
-	encryptedMeta, err := crypto.Encrypt(plainMeta, key)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt metadata: %w", err)
-	}
-
-	// Write to disk
-	return storage.WriteMetadata(v.rootPath, encryptedMeta)
+	return matches
 }
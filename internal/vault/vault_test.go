@@ -0,0 +1,43 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+func TestMatchFileNames(t *testing.T) {
+	files := []storage.FileMetadata{
+		{OriginalName: "config/app.yaml"},
+		{OriginalName: "config/App.yaml"},
+		{OriginalName: "config/db.yaml"},
+		{OriginalName: "notes.txt"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"config/app.yaml", []string{"config/app.yaml"}},
+		{"CONFIG/APP.YAML", []string{"config/app.yaml", "config/App.yaml"}},
+		{"yaml", []string{"config/app.yaml", "config/App.yaml", "config/db.yaml"}},
+		{"NOTES", []string{"notes.txt"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, i := range matchFileNames(files, tt.query) {
+			got = append(got, files[i].OriginalName)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("matchFileNames(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("matchFileNames(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// VerifyResult reports whether a local file is identical to its vaulted copy
+type VerifyResult struct {
+	Name   string // name of the vaulted file compared against
+	Match  bool
+	Reason string // why the files differ; empty when they match
+}
+
+// VerifyFile compares a file on disk with its copy in the vault
+// The vaulted copy is the file stored under name. Without a name, it is the
+// file stored under the local path (when relative) or its base name.
+func (s *Session) VerifyFile(localPath, name string) (*VerifyResult, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	fileMeta := ms.findLocal(localPath, name)
+	if fileMeta == nil {
+		return nil, ErrFileNotFound
+	}
+
+	match, reason, err := v.compareLocal(masterKey, fileMeta, localPath)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyResult{Name: fileMeta.OriginalName, Match: match, Reason: reason}, nil
+}
+
+// findLocal returns the vaulted copy of a local file: the file stored under
+// name or, without a name, under one of the local file's names (see localNames)
+func (ms *metaStore) findLocal(localPath, name string) *storage.FileMetadata {
+	if name != "" {
+		return ms.fileByName(name)
+	}
+	for _, candidate := range localNames(localPath) {
+		if fileMeta := ms.fileByName(candidate); fileMeta != nil {
+			return fileMeta
+		}
+	}
+	return nil
+}
+
+// localNames returns the names a local file may be stored under: its path,
+// if relative and inside the current directory, then its base name
+func localNames(localPath string) []string {
+	var names []string
+	cleaned := filepath.ToSlash(filepath.Clean(localPath))
+	if !filepath.IsAbs(localPath) && cleaned != ".." && !strings.HasPrefix(cleaned, "../") {
+		names = append(names, cleaned)
+	}
+	return append(names, filepath.Base(localPath))
+}
+
+// compareLocal reports whether a file on disk has the same contents as the
+// stored file, and if not, why
+// The stored hash is compared when there is one; older files are decrypted
+// and compared byte for byte.
+func (v *Vault) compareLocal(masterKey []byte, fileMeta *storage.FileMetadata, localPath string) (bool, string, error) {
+	data, info, err := storage.ReadPlaintextFile(localPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	localLink := info.Mode()&os.ModeSymlink != 0
+	if localLink || fileMeta.IsSymlink() {
+		if localLink != fileMeta.IsSymlink() {
+			return false, "one is a symbolic link and the other is not", nil
+		}
+		target, err := os.Readlink(localPath)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to read link: %w", err)
+		}
+		if target != fileMeta.LinkTarget {
+			return false, fmt.Sprintf("link target differs (local %s, vault %s)", target, fileMeta.LinkTarget), nil
+		}
+		return true, "", nil
+	}
+
+	if int64(len(data)) != fileMeta.Size {
+		return false, fmt.Sprintf("size differs (local %d bytes, vault %d bytes)", len(data), fileMeta.Size), nil
+	}
+
+	if fileMeta.SHA256 != "" {
+		if storage.ContentHash(data) != fileMeta.SHA256 {
+			return false, "content differs", nil
+		}
+		return true, "", nil
+	}
+
+	stored, err := v.loadFileData(masterKey, fileMeta)
+	if err != nil {
+		return false, "", err
+	}
+	if !bytes.Equal(data, stored) {
+		return false, "content differs", nil
+	}
+	return true, "", nil
+}
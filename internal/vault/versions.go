@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+var (
+	ErrVersionNotFound = errors.New("version not found")
+	ErrNoRetention     = errors.New("no retention rule given")
+)
+
+// PruneOptions selects which earlier versions prune keeps
+// A version is kept if any rule keeps it; the current version is always kept.
+type PruneOptions struct {
+	KeepLast   int           // keep the newest N versions of each file (0 disables)
+	KeepWithin time.Duration // keep versions added within this duration (0 disables)
+}
+
+// PruneResult summarizes a prune
+type PruneResult struct {
+	FilesPruned     int
+	VersionsRemoved int
+	ChunksDeleted   int
+}
+
+// ParseRetention parses a duration such as 36h, 30d or 2w
+// Besides the units of time.ParseDuration, d (days) and w (weeks) are
+// accepted. "never" returns 0.
+func ParseRetention(value string) (time.Duration, error) {
+	if value == "never" {
+		return 0, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if count, found := strings.CutSuffix(value, suffix); found {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid duration: %s", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+	return d, nil
+}
+
+// currentVersion describes a file's current contents as a version
+func currentVersion(fileMeta *storage.FileMetadata) storage.FileVersion {
+	version := fileMeta.Version
+	if version == 0 {
+		version = 1
+	}
+	addedAt := fileMeta.UpdatedAt
+	if addedAt.IsZero() {
+		addedAt = fileMeta.AddedAt
+	}
+	return storage.FileVersion{
+		Version: version,
+		Size:    fileMeta.Size,
+		ModTime: fileMeta.ModTime,
+		AddedAt: addedAt,
+		Chunks:  fileMeta.Chunks,
+		SHA256:  fileMeta.SHA256,
+
+		FileAttributes: fileMeta.FileAttributes,
+	}
+}
+
+// fileHistory returns every stored version of a file, oldest first
+func fileHistory(fileMeta *storage.FileMetadata) []storage.FileVersion {
+	history := append([]storage.FileVersion(nil), fileMeta.Versions...)
+	return append(history, currentVersion(fileMeta))
+}
+
+// fileChunkLists returns the chunk list of every stored version of a file
+func fileChunkLists(fileMeta *storage.FileMetadata) [][]string {
+	lists := make([][]string, 0, len(fileMeta.Versions)+1)
+	for _, version := range fileMeta.Versions {
+		lists = append(lists, version.Chunks)
+	}
+	return append(lists, fileMeta.Chunks)
+}
+
+// selectVersion returns a copy of the file metadata describing the given
+// version's contents (0 selects the current version)
+func selectVersion(fileMeta *storage.FileMetadata, version int) (*storage.FileMetadata, error) {
+	if version == 0 {
+		return fileMeta, nil
+	}
+
+	for _, fv := range fileHistory(fileMeta) {
+		if fv.Version == version {
+			selected := *fileMeta
+			selected.Size = fv.Size
+			selected.ModTime = fv.ModTime
+			selected.Chunks = fv.Chunks
+			selected.SHA256 = fv.SHA256
+			selected.FileAttributes = fv.FileAttributes
+			return &selected, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s has no version %d", ErrVersionNotFound, fileMeta.OriginalName, version)
+}
+
+// FileHistory returns the stored versions of a file, oldest first
+// Returns the actual filename that was matched (for fuzzy matching)
+func (s *Session) FileHistory(fileName string) (string, []storage.FileVersion, error) {
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Find the file with fuzzy matching
+	fileMeta := ms.findFile(fileName)
+	if fileMeta == nil {
+		return "", nil, ErrFileNotFound
+	}
+
+	return fileMeta.OriginalName, fileHistory(fileMeta), nil
+}
+
+// Prune removes earlier file versions not kept by the retention rules
+func (s *Session) Prune(opts PruneOptions) (*PruneResult, error) {
+	if opts.KeepLast <= 0 && opts.KeepWithin <= 0 {
+		return nil, ErrNoRetention
+	}
+
+	v, masterKey, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and decrypt metadata
+	ms, err := v.openMeta(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{}
+	cutoff := time.Now().Add(-opts.KeepWithin)
+
+	var unreferenced []string
+	for _, f := range ms.fileList() {
+		if len(f.Versions) == 0 {
+			continue
+		}
+
+		// Versions are oldest first; the current version counts as the newest
+		var kept []storage.FileVersion
+		for i, fv := range f.Versions {
+			newerCount := len(f.Versions) - i // versions newer than this one, including the current
+			keep := (opts.KeepLast > 0 && newerCount < opts.KeepLast) ||
+				(opts.KeepWithin > 0 && fv.AddedAt.After(cutoff))
+			if keep {
+				kept = append(kept, fv)
+				continue
+			}
+
+			unreferenced = append(unreferenced, ms.releaseChunks(fv.Chunks)...)
+			result.VersionsRemoved++
+		}
+
+		if len(kept) != len(f.Versions) {
+			f.Versions = kept
+			ms.putFile(f)
+			result.FilesPruned++
+		}
+	}
+
+	if err := ms.commit(); err != nil {
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	// Delete data only once the metadata no longer references it
+	v.deleteObjects(unreferenced)
+	result.ChunksDeleted = len(unreferenced)
+	return result, nil
+}
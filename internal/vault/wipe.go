@@ -0,0 +1,35 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+)
+
+// wiper securely deletes plaintext originals with the vault's wipe method
+// It warns once for each reason overwriting may not destroy the old data.
+type wiper struct {
+	method storage.WipeMethod
+	warned map[string]bool
+}
+
+// newWiper reads the wipe method from the vault config
+func (v *Vault) newWiper(ms *metaStore) (*wiper, error) {
+	method, err := storage.ParseWipeMethod(ms.vaultConfig().Wipe)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wipe method in config: %w", err)
+	}
+	return &wiper{method: method, warned: make(map[string]bool)}, nil
+}
+
+// delete securely deletes a file
+func (w *wiper) delete(filePath string) error {
+	if w.method != storage.WipeNone {
+		if reason := storage.WipeWarning(filePath); reason != "" && !w.warned[reason] {
+			w.warned[reason] = true
+			fmt.Fprintf(os.Stderr, "warning: overwriting may leave the plaintext recoverable: %s\n", reason)
+		}
+	}
+	return storage.SecureDelete(filePath, w.method)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -25,6 +26,32 @@ func main() {
 		err = cli.Add(args)
 	case "list":
 		err = cli.List(args)
+	case "log":
+		err = cli.Log(args)
+	case "status":
+		err = cli.Status(args)
+	case "verify":
+		err = cli.Verify(args)
+	case "update":
+		err = cli.Update(args)
+	case "cat":
+		err = cli.Cat(args)
+	case "exec":
+		err = cli.Exec(args)
+	case "env":
+		err = cli.Env(args)
+	case "secret":
+		err = cli.Secret(args)
+	case "render":
+		err = cli.Render(args)
+	case "edit":
+		err = cli.Edit(args)
+	case "prune":
+		err = cli.Prune(args)
+	case "stats":
+		err = cli.Stats(args)
+	case "repack":
+		err = cli.Repack(args)
 	case "extract":
 		err = cli.Extract(args)
 	case "drop":
@@ -33,8 +60,20 @@ func main() {
 		err = cli.Remove(args)
 	case "clear":
 		err = cli.Clear(args)
+	case "trash":
+		err = cli.Trash(args)
+	case "config":
+		err = cli.Config(args)
 	case "recover":
 		err = cli.Recover(args)
+	case "convert":
+		err = cli.Convert(args)
+	case "snapshot":
+		err = cli.Snapshot(args)
+	case "agent":
+		err = cli.Agent(args)
+	case "lock":
+		err = cli.Lock(args)
 	case "help", "-h", "--help":
 		cli.PrintUsage()
 		os.Exit(0)
@@ -45,6 +84,11 @@ func main() {
 	}
 
 	if err != nil {
+		// Pass on the exit status of a command run by exec
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,16 @@
+package vaultix
+
+import (
+	"github.com/Zayan-Mohamed/vaultix/internal/agentkey"
+	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+)
+
+// Master keys only move between sessions and the agent through agentkey
+func init() {
+	agentkey.Export = func(session any) ([]byte, error) {
+		return session.(*Session).s.AgentKey()
+	}
+	agentkey.Unlock = func(v any, key []byte) (any, error) {
+		return v.(*Vault).unlock(vault.UnlockedKey(key))
+	}
+}
@@ -0,0 +1,43 @@
+// Package vaultix opens and works with vaultix vaults from Go programs.
+//
+// A Vault is a vault directory, or a single-file container such as
+// secrets.vtx, on disk. Its contents can only be read or changed through a
+// Session, which Unlock returns once a credential has decrypted the vault's
+// master key:
+//
+//	v, err := vaultix.Open("/srv/app/secrets")
+//	if err != nil {
+//		return err
+//	}
+//	session, err := v.Unlock(vaultix.Password(password))
+//	if err != nil {
+//		return err // errors.Is(err, vaultix.ErrIncorrectPassword) for a wrong password
+//	}
+//	defer session.Close()
+//
+//	var config bytes.Buffer
+//	if err := session.ReadFile("config/database.yaml", &config, nil); err != nil {
+//		return err
+//	}
+//
+// The password is only stretched (with Argon2id) once per session, so a
+// session should be kept for a batch of operations and closed when done;
+// Close zeroes the master key. Each operation reads the vault's metadata
+// afresh, so a session sees changes made by other processes, such as the
+// vaultix command. A session is not safe for concurrent use.
+//
+// # Errors
+//
+// Errors that callers may want to handle are exported as Err values. They may
+// be wrapped with more context, so compare them with errors.Is rather than ==,
+// and never by message.
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are not removed or renamed and their behavior does not change
+// incompatibly; new functions, methods, struct fields, constants and Err
+// values may be added, so construct structs with field names. The packages
+// under internal/ are the implementation and carry no such promise. Vaults
+// written by one release remain readable by later ones.
+package vaultix
@@ -0,0 +1,54 @@
+package vaultix
+
+import (
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+)
+
+// Errors returned by the package, possibly wrapped; test for them with
+// errors.Is
+var (
+	// ErrVaultNotFound is returned by Open when there is no vault at the path
+	ErrVaultNotFound = storage.ErrVaultNotFound
+	// ErrVaultExists is returned by Init when a vault already exists
+	ErrVaultExists = storage.ErrVaultExists
+	// ErrIncorrectPassword is returned by Unlock for a wrong password or
+	// recovery key
+	ErrIncorrectPassword = crypto.ErrInvalidPassword
+	// ErrCorrupted is returned when stored data is too short to decrypt.
+	// Data that fails authentication is reported as ErrIncorrectPassword, as
+	// the two cannot be told apart.
+	ErrCorrupted = crypto.ErrCorruptedData
+	// ErrHashMismatch is returned when decrypted contents do not match the
+	// digest recorded when they were added
+	ErrHashMismatch = vault.ErrHashMismatch
+	// ErrSessionClosed is returned by the methods of a closed Session
+	ErrSessionClosed = vault.ErrSessionClosed
+
+	// ErrFileNotFound is returned when no stored file matches a name
+	ErrFileNotFound = vault.ErrFileNotFound
+	// ErrSymlink is returned when reading the contents of a stored
+	// symbolic link
+	ErrSymlink = vault.ErrSymlink
+	// ErrUnsafePath is returned for file names that could write outside a
+	// destination directory; see ValidateFileName
+	ErrUnsafePath = storage.ErrUnsafePath
+	// ErrVersionNotFound is returned for a file version that is not kept
+	ErrVersionNotFound = vault.ErrVersionNotFound
+	// ErrNoRetention is returned by Prune when no retention rule is given
+	ErrNoRetention = vault.ErrNoRetention
+
+	// ErrSnapshotNotFound is returned when no snapshot matches an ID
+	ErrSnapshotNotFound = vault.ErrSnapshotNotFound
+	// ErrSnapshotAmbiguous is returned when an ID prefix matches several
+	// snapshots
+	ErrSnapshotAmbiguous = vault.ErrSnapshotAmbiguous
+	// ErrNotInTrash is returned when no file in the trash matches
+	ErrNotInTrash = vault.ErrNotInTrash
+	// ErrSecretNotFound is returned when no secret entry has a name
+	ErrSecretNotFound = vault.ErrSecretNotFound
+	// ErrNoMemoryTempDir is returned by EditFile when no memory-backed
+	// directory is available for the decrypted copy
+	ErrNoMemoryTempDir = storage.ErrNoMemoryTempDir
+)
@@ -0,0 +1,459 @@
+package vaultix
+
+import (
+	"io"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+)
+
+// Session is an unlocked vault, holding its master key until Close
+// Files are named by their slash-separated path in the vault. Methods taking
+// a file name to read accept fuzzy matches (a unique base name or partial
+// name) and report the name actually matched. Methods that remove or restore
+// a file, and names being written, need the exact name; FindFiles and
+// FindTrash list what a partial name could mean. A session is not safe for
+// concurrent use.
+type Session struct {
+	vault *Vault
+	s     *vault.Session
+}
+
+// Close zeroes the session's copy of the master key
+// Methods called after Close return ErrSessionClosed. Close may be called
+// more than once.
+func (s *Session) Close() error {
+	return s.s.Close()
+}
+
+// Vault returns the vault the session unlocked
+func (s *Session) Vault() *Vault {
+	return s.vault
+}
+
+// SetProgressCallback sets a function called as files are processed by
+// AddDirectory, ExtractAllFiles, DropAllFiles and UpdateAll, or nil for none
+func (s *Session) SetProgressCallback(progress ProgressFunc) {
+	s.s.SetProgressCallback(progress)
+}
+
+// AddFile encrypts a file on disk, stores it under its base name and
+// securely deletes the original
+// If a file with the same name is already stored, its contents become a new
+// version of that file. Returns the version number stored.
+func (s *Session) AddFile(filePath string) (int, error) {
+	return s.s.AddFile(filePath)
+}
+
+// AddReader encrypts everything read from r and stores it under name, as a
+// new file or as the next version of an existing one
+// A new file is stored without attributes, so it is extracted with mode 0600;
+// a new version keeps the attributes of the current one. The modification
+// time is the time of adding. Returns the version number stored.
+func (s *Session) AddReader(name string, r io.Reader) (int, error) {
+	return s.s.AddReader(name, r)
+}
+
+// AddDirectory encrypts and stores every file under a directory, recursively,
+// and securely deletes the originals
+// Files are stored under their path relative to the directory's parent, so
+// adding "certs" stores "certs/prod/server.pem". Adding the vault directory
+// itself stores paths relative to it. Only files selected by the filter are
+// added. Returns the number of files added.
+func (s *Session) AddDirectory(dirPath string, filter *FileFilter) (int, error) {
+	return s.s.AddDirectory(dirPath, filter.internal())
+}
+
+// ListFiles returns the files in the vault
+func (s *Session) ListFiles() ([]FileInfo, error) {
+	files, err := s.s.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfos(files), nil
+}
+
+// FileHistory returns the stored versions of a file, oldest first, and the
+// name matched
+func (s *Session) FileHistory(fileName string) (string, []FileVersion, error) {
+	name, versions, err := s.s.FileHistory(fileName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	history := make([]FileVersion, len(versions))
+	for i, version := range versions {
+		history[i] = FileVersion{
+			Version: version.Version,
+			Size:    version.Size,
+			Mode:    version.Mode,
+			ModTime: version.ModTime,
+			AddedAt: version.AddedAt,
+		}
+	}
+	return name, history, nil
+}
+
+// ReadFile decrypts a stored file and writes its contents to w, without
+// writing anything to disk
+// opts.Snapshot and opts.Version select earlier contents. Nothing is written
+// to w unless the whole file decrypts and matches its stored hash. Symbolic
+// links have no contents to write and are refused with ErrSymlink.
+func (s *Session) ReadFile(fileName string, w io.Writer, opts *ExtractOptions) error {
+	return s.s.ReadFile(fileName, w, opts.internal())
+}
+
+// ReadFiles decrypts several stored files into memory and returns their
+// contents in the order of fileNames
+// Files are found as in ReadFile.
+func (s *Session) ReadFiles(fileNames []string, opts *ExtractOptions) ([][]byte, error) {
+	return s.s.ReadFiles(fileNames, opts.internal())
+}
+
+// ExtractFile decrypts a stored file to destPath, restoring its attributes
+// The result names the file matched, as extracted or skipped.
+func (s *Session) ExtractFile(fileName, destPath string, opts *ExtractOptions) (*ExtractResult, error) {
+	result, err := s.s.ExtractFile(fileName, destPath, opts.internal())
+	return newExtractResult(result), err
+}
+
+// ExtractAllFiles decrypts every stored file below destDir
+// On error, the result describes the files handled so far.
+func (s *Session) ExtractAllFiles(destDir string, opts *ExtractOptions) (*ExtractResult, error) {
+	result, err := s.s.ExtractAllFiles(destDir, opts.internal())
+	return newExtractResult(result), err
+}
+
+// DropFile extracts the file with exactly this name and then removes it from
+// the vault
+// Returns ErrFileNotFound unless the name is exact. The file is moved to the
+// trash unless permanent is set. A file skipped because of a conflict stays in
+// the vault. Drop always works on the current files, so opts.Snapshot and
+// opts.Version are ignored.
+func (s *Session) DropFile(fileName, destPath string, opts *ExtractOptions, permanent bool) (*ExtractResult, error) {
+	result, err := s.s.DropFile(fileName, destPath, opts.internal(), permanent)
+	return newExtractResult(result), err
+}
+
+// DropAllFiles extracts all files and then removes them from the vault
+// As in DropFile, files skipped because of a conflict stay in the vault. On
+// error, the result describes the files handled so far.
+func (s *Session) DropAllFiles(destDir string, opts *ExtractOptions, permanent bool) (*ExtractResult, error) {
+	result, err := s.s.DropAllFiles(destDir, opts.internal(), permanent)
+	return newExtractResult(result), err
+}
+
+// RemoveFile removes the file with exactly this name from the vault
+// The file is moved to the trash unless permanent is set.
+func (s *Session) RemoveFile(fileName string, permanent bool) error {
+	return s.s.RemoveFile(fileName, permanent)
+}
+
+// FindFiles returns the names of the files a partial or differently cased
+// name could mean, for resolving it before RemoveFile or DropFile
+// An exact name gives only itself; no match gives an empty list.
+func (s *Session) FindFiles(query string) ([]string, error) {
+	return s.s.FindFiles(query)
+}
+
+// Clear removes all files from the vault without extracting them
+// The files are moved to the trash unless permanent is set.
+func (s *Session) Clear(permanent bool) error {
+	return s.s.ClearVault(permanent)
+}
+
+// VerifyFile compares a file on disk with its stored copy
+// The stored copy is the file stored under name. Without a name, it is the
+// file stored under the local path (when relative) or its base name.
+func (s *Session) VerifyFile(localPath, name string) (*VerifyResult, error) {
+	result, err := s.s.VerifyFile(localPath, name)
+	if err != nil {
+		return nil, err
+	}
+	return (*VerifyResult)(result), nil
+}
+
+// Status compares the files in a directory with the vault, matching each
+// stored file with the file at its name below dir
+// Files on disk that are not in the vault are found the way Init finds them,
+// so hidden and ignored files are not reported as new. Results are sorted by
+// name.
+func (s *Session) Status(dir string) ([]FileStatus, error) {
+	statuses, err := s.s.Status(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]FileStatus, len(statuses))
+	for i, status := range statuses {
+		converted[i] = FileStatus{Name: status.Name, Path: status.Path, State: FileState(status.State)}
+	}
+	return converted, nil
+}
+
+// UpdateFile stores the contents of a local file as the next version of a
+// stored file, which must already exist
+// The stored file is found as in VerifyFile. The local file is left in place.
+func (s *Session) UpdateFile(localPath, name string) (*UpdateResult, error) {
+	result, err := s.s.UpdateFile(localPath, name)
+	if err != nil {
+		return nil, err
+	}
+	return (*UpdateResult)(result), nil
+}
+
+// UpdateAll stores every modified file in a directory as a new version
+// Files are matched and classified as in Status. Returns the files updated,
+// followed by those skipped as StateNotFile (with NotFile set).
+func (s *Session) UpdateAll(dir string) ([]UpdateResult, error) {
+	results, err := s.s.UpdateAll(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]UpdateResult, len(results))
+	for i, result := range results {
+		converted[i] = UpdateResult(result)
+	}
+	return converted, nil
+}
+
+// EditFile decrypts a stored file into a private temporary directory, calls
+// edit with the path of the decrypted copy, and stores the result as the next
+// version if its contents changed
+// The directory is created in tempBase, or on a memory-backed filesystem if
+// tempBase is "" (failing with ErrNoMemoryTempDir if there is none). It is
+// wiped before EditFile returns, whether or not edit succeeds. If edit returns
+// an error, nothing is stored.
+func (s *Session) EditFile(name, tempBase string, edit func(path string) error) (*UpdateResult, error) {
+	result, err := s.s.EditFile(name, tempBase, edit)
+	if err != nil {
+		return nil, err
+	}
+	return (*UpdateResult)(result), nil
+}
+
+// Prune removes earlier file versions not kept by the retention rules
+// Returns an error wrapping ErrNoRetention if opts sets no rule.
+func (s *Session) Prune(opts PruneOptions) (*PruneResult, error) {
+	result, err := s.s.Prune(vault.PruneOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return (*PruneResult)(result), nil
+}
+
+// Stats returns size and deduplication statistics for the vault
+func (s *Session) Stats() (*Stats, error) {
+	stats, err := s.s.Stats()
+	if err != nil {
+		return nil, err
+	}
+	return (*Stats)(stats), nil
+}
+
+// Repack compacts pack files after removals and packs small loose objects
+func (s *Session) Repack() (*RepackResult, error) {
+	result, err := s.s.Repack()
+	if err != nil {
+		return nil, err
+	}
+	return (*RepackResult)(result), nil
+}
+
+// Convert copies the vault to destPath, which may use the other storage layout
+// (a directory vault or a single-file container); the source is left untouched
+// The copy has the same password and recovery key.
+func (s *Session) Convert(destPath string) error {
+	return s.s.Convert(destPath)
+}
+
+// Config holds a vault's settings
+// The config is kept in the vault's encrypted metadata, so reading or
+// changing it needs an unlocked session.
+type Config struct {
+	// TrashRetention is how long removed files are kept in the trash, as a
+	// duration such as "36h", "30d" or "2w", or "never" to keep them until
+	// the trash is emptied
+	TrashRetention string
+	// Wipe is how the originals of encrypted files are destroyed: "none",
+	// "single" or "multi" pass overwrite
+	Wipe string
+}
+
+// Config returns the vault's settings, with defaults for those not set
+func (s *Session) Config() (*Config, error) {
+	config, err := s.s.Config()
+	if err != nil {
+		return nil, err
+	}
+	return &Config{TrashRetention: config.TrashRetention, Wipe: config.Wipe}, nil
+}
+
+// SetConfig checks and stores the vault's settings
+// Empty values select the defaults.
+func (s *Session) SetConfig(config *Config) error {
+	return s.s.SetConfig(&storage.VaultConfig{TrashRetention: config.TrashRetention, Wipe: config.Wipe})
+}
+
+// CreateSnapshot records the current files as a new snapshot
+func (s *Session) CreateSnapshot(description string) (*Snapshot, error) {
+	snapshot, err := s.s.CreateSnapshot(description)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(snapshot), nil
+}
+
+// ListSnapshots returns all snapshots, oldest first
+func (s *Session) ListSnapshots() ([]Snapshot, error) {
+	snapshots, err := s.s.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]Snapshot, len(snapshots))
+	for i := range snapshots {
+		converted[i] = *newSnapshot(&snapshots[i])
+	}
+	return converted, nil
+}
+
+// RestoreSnapshot replaces the current files with those of a snapshot
+// The current files are saved as a new snapshot first, so a restore can
+// itself be undone. Returns that snapshot.
+func (s *Session) RestoreSnapshot(snapshotID string) (*Snapshot, error) {
+	snapshot, err := s.s.RestoreSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(snapshot), nil
+}
+
+// DeleteSnapshot removes a snapshot and any data only it referenced, and
+// returns the snapshot removed
+func (s *Session) DeleteSnapshot(snapshotID string) (*Snapshot, error) {
+	snapshot, err := s.s.DeleteSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(snapshot), nil
+}
+
+// ListTrash returns the files in the trash, oldest removal first
+// Files past the vault's trash retention are deleted first.
+func (s *Session) ListTrash() ([]TrashEntry, error) {
+	entries, err := s.s.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]TrashEntry, len(entries))
+	for i := range entries {
+		converted[i] = *newTrashEntry(&entries[i])
+	}
+	return converted, nil
+}
+
+// RestoreFromTrash moves a file from the trash back into the vault
+// The query is a trash entry ID or an exact file name; if the file was
+// removed more than once, the most recent removal is restored.
+func (s *Session) RestoreFromTrash(query string) (*TrashEntry, error) {
+	entry, err := s.s.RestoreFromTrash(query)
+	if err != nil {
+		return nil, err
+	}
+	return newTrashEntry(entry), nil
+}
+
+// FindTrash returns the trash entries a query could mean, newest removal
+// first, for resolving it before RestoreFromTrash
+// An entry ID or exact file name gives only the entry RestoreFromTrash would
+// restore; otherwise the entries whose ID starts with the query, or else
+// whose name matches it fuzzily, are returned.
+func (s *Session) FindTrash(query string) ([]TrashEntry, error) {
+	entries, err := s.s.FindTrash(query)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]TrashEntry, len(entries))
+	for i := range entries {
+		converted[i] = *newTrashEntry(&entries[i])
+	}
+	return converted, nil
+}
+
+// EmptyTrash permanently deletes every file in the trash
+// Returns the number of files deleted.
+func (s *Session) EmptyTrash() (int, error) {
+	return s.s.EmptyTrash()
+}
+
+// SetSecret creates a secret entry or changes an existing one
+// Each field in fields is set; an empty value removes the field. An empty
+// secretType keeps the entry's type, or makes a new entry a login.
+func (s *Session) SetSecret(name string, secretType SecretType, fields map[string]string) (*Secret, error) {
+	secret, err := s.s.SetSecret(name, storage.SecretType(secretType), fields)
+	if err != nil {
+		return nil, err
+	}
+	return newSecret(secret), nil
+}
+
+// GetSecret returns a secret entry and its field values
+func (s *Session) GetSecret(name string) (*Secret, *SecretFields, error) {
+	secret, fields, err := s.s.GetSecret(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newSecret(secret), (*SecretFields)(fields), nil
+}
+
+// ListSecrets returns all secret entries sorted by name, without their values
+func (s *Session) ListSecrets() ([]Secret, error) {
+	secrets, err := s.s.ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]Secret, len(secrets))
+	for i := range secrets {
+		converted[i] = *newSecret(&secrets[i])
+	}
+	return converted, nil
+}
+
+// RemoveSecret deletes a secret entry and its values permanently
+func (s *Session) RemoveSecret(name string) error {
+	return s.s.RemoveSecret(name)
+}
+
+// ReadEnv decrypts dotenv files from the vault and returns their variables,
+// merged with MergeEnv
+// Files are found as in ReadFile.
+func (s *Session) ReadEnv(fileNames []string) ([]EnvVar, error) {
+	vars, err := s.s.ReadEnv(fileNames)
+	if err != nil {
+		return nil, err
+	}
+	return newEnvVars(vars), nil
+}
+
+// RenderTemplate executes a text/template with functions resolving vault
+// contents and writes the output to w
+// The functions are file, secret and dotenv, which read stored files by their
+// exact names (secret also reads fields of secret entries), and the helpers
+// b64enc, b64dec, quote, trim and indent. Nothing is written to w unless the
+// whole template executes, so a missing reference never produces partial
+// output.
+func (s *Session) RenderTemplate(name, text string, w io.Writer) error {
+	return s.s.RenderTemplate(name, text, w)
+}
+
+// CheckTemplate executes a template as RenderTemplate does, discarding the
+// output, and returns the references it makes that the vault cannot resolve,
+// sorted
+func (s *Session) CheckTemplate(name, text string) ([]string, error) {
+	return s.s.CheckTemplate(name, text)
+}
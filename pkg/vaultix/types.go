@@ -0,0 +1,436 @@
+package vaultix
+
+import (
+	"os"
+	"time"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+)
+
+// ContainerExt is the file extension of single-file vaults
+const ContainerExt = storage.ContainerExt
+
+// ProgressFunc is called as a long operation handles each item
+type ProgressFunc func(current, total int, message string)
+
+// FileInfo describes a file stored in a vault
+type FileInfo struct {
+	Name       string      // path in the vault, slash-separated
+	Size       int64       // size of the contents in bytes
+	Mode       os.FileMode // permission bits, plus os.ModeSymlink for links; 0 if not recorded
+	ModTime    time.Time   // modification time of the file when it was stored
+	LinkTarget string      // target of a symbolic link
+	Version    int         // number of the current contents, starting at 1
+	AddedAt    time.Time   // when the file was first added
+	UpdatedAt  time.Time   // when the current contents were stored
+	SHA256     string      // hex SHA-256 digest of the contents; empty for old files
+}
+
+// IsSymlink reports whether the file is a symbolic link
+func (f *FileInfo) IsSymlink() bool {
+	return f.Mode&os.ModeSymlink != 0
+}
+
+// newFileInfo converts stored file metadata
+func newFileInfo(f *storage.FileMetadata) FileInfo {
+	version := f.Version
+	if version == 0 {
+		version = 1
+	}
+	updatedAt := f.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = f.AddedAt
+	}
+	return FileInfo{
+		Name:       f.OriginalName,
+		Size:       f.Size,
+		Mode:       f.Mode,
+		ModTime:    f.ModTime,
+		LinkTarget: f.LinkTarget,
+		Version:    version,
+		AddedAt:    f.AddedAt,
+		UpdatedAt:  updatedAt,
+		SHA256:     f.SHA256,
+	}
+}
+
+// newFileInfos converts a list of stored file metadata
+func newFileInfos(files []storage.FileMetadata) []FileInfo {
+	infos := make([]FileInfo, len(files))
+	for i := range files {
+		infos[i] = newFileInfo(&files[i])
+	}
+	return infos
+}
+
+// FileVersion describes one stored version of a file's contents
+type FileVersion struct {
+	Version int         // version number, starting at 1
+	Size    int64       // size of the contents in bytes
+	Mode    os.FileMode // as in FileInfo
+	ModTime time.Time   // modification time of the file when it was stored
+	AddedAt time.Time   // when this version was stored
+}
+
+// ConflictPolicy selects what extraction does when an output file already exists
+type ConflictPolicy string
+
+const (
+	ConflictAsk       ConflictPolicy = "ask"       // decide per file through ExtractOptions.Ask
+	ConflictSkip      ConflictPolicy = "skip"      // keep the existing file
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace the existing file
+	ConflictRename    ConflictPolicy = "rename"    // write beside it as "name (1).ext"
+	ConflictNewer     ConflictPolicy = "newer"     // replace it only if the stored file is newer
+)
+
+// ParseConflictPolicy checks a conflict policy name
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	policy, err := vault.ParseConflictPolicy(value)
+	return ConflictPolicy(policy), err
+}
+
+// RestoreOptions selects which file attributes extraction restores besides
+// permissions
+type RestoreOptions struct {
+	NoOwner  bool // do not restore uid/gid (only restored when running as root)
+	NoXattrs bool // do not restore extended attributes
+}
+
+// ExtractOptions selects which stored contents are read, and how extraction
+// writes them
+// A nil *ExtractOptions selects the current contents and overwrites existing
+// files.
+type ExtractOptions struct {
+	Snapshot string // snapshot ID or unique prefix; empty means the current files
+	Version  int    // file version to read; 0 means the latest
+	// Restore selects which file attributes are restored
+	Restore RestoreOptions
+	// OnConflict selects what happens when an output file already exists;
+	// empty means overwrite
+	OnConflict ConflictPolicy
+	// Ask decides each conflict under ConflictAsk, returning skip, overwrite,
+	// rename or newer. Without it, conflicts are skipped.
+	Ask func(outputPath string, stored *FileInfo, existing os.FileInfo) ConflictPolicy
+}
+
+// internal converts the options for the vault implementation
+func (o *ExtractOptions) internal() vault.ExtractOptions {
+	if o == nil {
+		return vault.ExtractOptions{}
+	}
+
+	opts := vault.ExtractOptions{
+		Snapshot:   o.Snapshot,
+		Version:    o.Version,
+		Restore:    storage.RestoreOptions(o.Restore),
+		OnConflict: vault.ConflictPolicy(o.OnConflict),
+	}
+	if ask := o.Ask; ask != nil {
+		opts.Ask = func(outputPath string, stored *storage.FileMetadata, existing os.FileInfo) vault.ConflictPolicy {
+			info := newFileInfo(stored)
+			return vault.ConflictPolicy(ask(outputPath, &info, existing))
+		}
+	}
+	return opts
+}
+
+// ExtractResult summarizes an extraction
+type ExtractResult struct {
+	Extracted []string      // names of the files written
+	Skipped   []string      // names of files not written because the output existed
+	Renamed   []RenamedFile // files written to another path because the output existed
+}
+
+// RenamedFile is a file written under a new path to avoid overwriting another
+type RenamedFile struct {
+	Name string // name in the vault
+	Path string // path written
+}
+
+// newExtractResult converts an extraction result
+func newExtractResult(r *vault.ExtractResult) *ExtractResult {
+	if r == nil {
+		return nil
+	}
+	result := &ExtractResult{Extracted: r.Extracted, Skipped: r.Skipped}
+	for _, renamed := range r.Renamed {
+		result.Renamed = append(result.Renamed, RenamedFile(renamed))
+	}
+	return result
+}
+
+// FileFilter selects which files Init and AddDirectory encrypt, on top of the
+// .vaultixignore files found in the directory
+// A nil *FileFilter selects all files except dotfiles.
+type FileFilter struct {
+	// Include, when set, selects only files matching one of the patterns
+	// (a pattern matching a directory selects everything inside it)
+	Include []string
+	// Exclude patterns are applied after those in .vaultixignore, so they
+	// take precedence over its ! (re-include) lines
+	Exclude []string
+	// Hidden includes dotfiles and dot-directories, which are skipped by default
+	Hidden bool
+}
+
+// internal converts the filter for the vault implementation
+func (f *FileFilter) internal() *storage.FileFilter {
+	if f == nil {
+		return nil
+	}
+	return (*storage.FileFilter)(f)
+}
+
+// TreePlan lists what Init or AddDirectory would do, without doing it
+type TreePlan struct {
+	Files       []PlannedFile // files that would be encrypted and securely deleted
+	RemovedDirs []string      // directories that would be left empty and removed
+}
+
+// PlannedFile is a file that would be encrypted
+type PlannedFile struct {
+	Path string // path on disk
+	Name string // name it would be stored under in the vault
+}
+
+// newTreePlan converts a plan
+func newTreePlan(p *vault.TreePlan) *TreePlan {
+	plan := &TreePlan{RemovedDirs: p.RemovedDirs}
+	for _, file := range p.Files {
+		plan.Files = append(plan.Files, PlannedFile(file))
+	}
+	return plan
+}
+
+// UpdateResult reports the outcome of storing a local file as a new version
+type UpdateResult struct {
+	Name    string // name in the vault
+	Path    string // local file read
+	Version int    // version now current
+	Changed bool   // false if the local file matched the vault and nothing was stored
+	NotFile bool   // skipped because something other than a file is at Path
+}
+
+// VerifyResult reports whether a local file is identical to its stored copy
+type VerifyResult struct {
+	Name   string // name of the stored file compared against
+	Match  bool
+	Reason string // why the files differ; empty when they match
+}
+
+// FileState classifies a file when comparing a directory with the vault
+type FileState string
+
+const (
+	StateUnchanged FileState = "unchanged"  // on disk and identical to the stored copy
+	StateModified  FileState = "modified"   // on disk and different from the stored copy
+	StateNew       FileState = "new"        // on disk but not in the vault
+	StateVaultOnly FileState = "vault-only" // in the vault but not on disk
+	StateNotFile   FileState = "not-a-file" // on disk as something else, such as a directory
+)
+
+// FileStatus is the state of one file when comparing a directory with the vault
+type FileStatus struct {
+	Name  string // name in the vault, the path relative to the directory
+	Path  string // path on disk
+	State FileState
+}
+
+// PruneOptions selects which earlier versions Prune keeps
+// A version is kept if any rule keeps it; the current version is always kept.
+type PruneOptions struct {
+	KeepLast   int           // keep the newest N versions of each file (0 disables)
+	KeepWithin time.Duration // keep versions added within this duration (0 disables)
+}
+
+// ParseRetention parses a retention period such as 36h, 30d or 2w
+// Besides the units of time.ParseDuration, d (days) and w (weeks) are
+// accepted. "never" returns 0.
+func ParseRetention(value string) (time.Duration, error) {
+	return vault.ParseRetention(value)
+}
+
+// PruneResult summarizes a prune
+type PruneResult struct {
+	FilesPruned     int // files that lost at least one version
+	VersionsRemoved int // versions removed
+	ChunksDeleted   int // stored chunks deleted as no longer referenced
+}
+
+// Stats describes the vault's size and deduplication
+// LogicalSize and StoredSize both cover the current files only; chunks kept
+// only for earlier versions, snapshots and the trash are counted in
+// HistorySize.
+type Stats struct {
+	Files       int   // number of files in the vault
+	Chunks      int   // number of unique stored chunks, including history
+	LogicalSize int64 // total size of the current files
+	StoredSize  int64 // plaintext bytes of the unique chunks of the current files
+	HistorySize int64 // plaintext bytes of chunks kept only for history
+}
+
+// DedupRatio returns logical size divided by stored size (1.0 means no savings)
+func (s *Stats) DedupRatio() float64 {
+	return (*vault.Stats)(s).DedupRatio()
+}
+
+// RepackResult summarizes a repack
+type RepackResult struct {
+	PacksRewritten int   // packs that were compacted away
+	ObjectsPacked  int   // loose objects moved into packs
+	BytesReclaimed int64 // unreferenced bytes removed from packs
+}
+
+// Snapshot describes a saved, immutable view of the vault's files
+type Snapshot struct {
+	ID          string    // random hex ID; a unique prefix also identifies the snapshot
+	Description string    // message given when the snapshot was created
+	CreatedAt   time.Time // when the snapshot was created
+	FileCount   int       // number of files in the snapshot
+	TotalSize   int64     // total size of the files in bytes
+}
+
+// newSnapshot converts snapshot metadata
+func newSnapshot(s *storage.SnapshotMetadata) *Snapshot {
+	if s == nil {
+		return nil
+	}
+	return &Snapshot{
+		ID:          s.ID,
+		Description: s.Description,
+		CreatedAt:   s.CreatedAt,
+		FileCount:   s.FileCount,
+		TotalSize:   s.TotalSize,
+	}
+}
+
+// TrashEntry is a removed file kept until the trash expires or is emptied
+type TrashEntry struct {
+	ID        string    // random hex ID; a unique prefix also identifies the entry
+	DeletedAt time.Time // when the file was removed
+	File      FileInfo  // the file as it was when removed
+}
+
+// newTrashEntry converts a trash entry
+func newTrashEntry(e *storage.TrashEntry) *TrashEntry {
+	if e == nil {
+		return nil
+	}
+	return &TrashEntry{ID: e.ID, DeletedAt: e.DeletedAt, File: newFileInfo(&e.File)}
+}
+
+// SecretType classifies a secret entry
+type SecretType string
+
+const (
+	SecretLogin SecretType = "login" // username, password and URL
+	SecretNote  SecretType = "note"  // free text in the notes field
+	SecretToken SecretType = "token" // an API token, kept in the password field
+)
+
+// ParseSecretType checks a secret type name
+func ParseSecretType(value string) (SecretType, error) {
+	secretType, err := storage.ParseSecretType(value)
+	return SecretType(secretType), err
+}
+
+// Secret describes a secret entry without its values
+type Secret struct {
+	Name       string     // name of the entry
+	Type       SecretType // kind of entry
+	CreatedAt  time.Time  // when the entry was created
+	UpdatedAt  time.Time  // when a field was last changed
+	FieldNames []string   // fields set: standard fields first, then custom fields sorted
+}
+
+// newSecret converts secret metadata
+func newSecret(s *storage.SecretMetadata) *Secret {
+	if s == nil {
+		return nil
+	}
+	return &Secret{
+		Name:       s.Name,
+		Type:       SecretType(s.Type),
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+		FieldNames: s.FieldNames,
+	}
+}
+
+// SecretFields holds the values of a secret entry's fields
+type SecretFields struct {
+	Username string
+	Password string
+	URL      string
+	Notes    string
+	Custom   map[string]string // custom fields by name
+}
+
+// Get returns the value of a standard (username, password, url, notes) or
+// custom field, and whether it is set
+func (f *SecretFields) Get(name string) (string, bool) {
+	return (*storage.SecretFields)(f).Get(name)
+}
+
+// Names returns the names of the fields set: standard fields first, then
+// custom fields sorted
+func (f *SecretFields) Names() []string {
+	return (*storage.SecretFields)(f).Names()
+}
+
+// ValidateSecretField checks a field name: letters, digits, '_' and '-'
+func ValidateSecretField(name string) error {
+	return storage.ValidateSecretField(name)
+}
+
+// ValidateFileName checks a name for storing a file under
+// A name is a relative, slash-separated path without empty, "." or ".."
+// elements, backslashes or control characters. Errors wrap ErrUnsafePath.
+func ValidateFileName(name string) error {
+	return storage.ValidateFileName(name)
+}
+
+// EnvVar is an environment variable read from a dotenv file
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// ParseDotenv parses the contents of a dotenv file
+// Each line is KEY=VALUE, optionally preceded by "export". Blank lines and
+// lines starting with # are ignored. Values may be:
+//   - unquoted: surrounding whitespace is trimmed and " #" starts a comment
+//   - 'single-quoted': taken literally, and may span lines
+//   - "double-quoted": \n, \r, \t, \", \\ and \$ are unescaped, and the value
+//     may span lines
+//
+// Variables are not expanded. Errors give the line number but never a value.
+func ParseDotenv(data []byte) ([]EnvVar, error) {
+	vars, err := vault.ParseDotenv(data)
+	return newEnvVars(vars), err
+}
+
+// MergeEnv merges lists of variables; a later value for a key replaces an
+// earlier one. Keys keep the order in which they first appear.
+func MergeEnv(lists ...[]EnvVar) []EnvVar {
+	converted := make([][]vault.EnvVar, len(lists))
+	for i, vars := range lists {
+		for _, v := range vars {
+			converted[i] = append(converted[i], vault.EnvVar(v))
+		}
+	}
+	return newEnvVars(vault.MergeEnv(converted...))
+}
+
+// newEnvVars converts a list of variables
+func newEnvVars(vars []vault.EnvVar) []EnvVar {
+	if vars == nil {
+		return nil
+	}
+	converted := make([]EnvVar, len(vars))
+	for i, v := range vars {
+		converted[i] = EnvVar(v)
+	}
+	return converted
+}
@@ -0,0 +1,184 @@
+package vaultix
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zayan-Mohamed/vaultix/internal/crypto"
+	"github.com/Zayan-Mohamed/vaultix/internal/storage"
+	"github.com/Zayan-Mohamed/vaultix/internal/vault"
+)
+
+// Vault is a vault on disk: a directory holding a .vaultix directory, or a
+// single-file container such as secrets.vtx
+// A Vault holds no keys; use Unlock to work with its contents.
+type Vault struct {
+	path string
+	v    *vault.Vault
+}
+
+// Exists reports whether a vault exists at path
+func Exists(path string) bool {
+	return storage.VaultExists(path)
+}
+
+// IsContainer reports whether path refers to a single-file container rather
+// than a vault directory: a path ending in ContainerExt, or an existing file
+// that starts with the container header
+func IsContainer(path string) bool {
+	return storage.IsContainer(path)
+}
+
+// Open returns the vault at path, which is made absolute
+// Returns an error wrapping ErrVaultNotFound if there is none.
+func Open(path string) (*Vault, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault path: %w", err)
+	}
+	if !storage.VaultExists(absPath) {
+		return nil, fmt.Errorf("%w: %s", ErrVaultNotFound, absPath)
+	}
+	return &Vault{path: absPath, v: vault.New(absPath)}, nil
+}
+
+// InitOptions are the options of Init
+type InitOptions struct {
+	// Filter selects the files to encrypt; nil selects all except dotfiles
+	Filter *FileFilter
+	// Progress, if set, is called as each file is encrypted
+	Progress ProgressFunc
+}
+
+// Init creates a vault at path protected by password, encrypting the files in
+// the directory and securely deleting the originals
+// For a container path (ending in ContainerExt), the files of the directory
+// holding it are encrypted. Returns the vault and its recovery key, which can
+// unlock the vault if the password is lost and is not stored anywhere; the
+// caller must show or save it.
+func Init(path, password string, opts *InitOptions) (*Vault, RecoveryKey, error) {
+	if opts == nil {
+		opts = &InitOptions{}
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid vault path: %w", err)
+	}
+
+	v := vault.New(absPath)
+	if opts.Progress != nil {
+		v.SetProgressCallback(opts.Progress)
+	}
+	recoveryKey, err := v.Initialize(password, opts.Filter.internal())
+	if err != nil {
+		return nil, nil, err
+	}
+	v.SetProgressCallback(nil)
+
+	return &Vault{path: absPath, v: v}, RecoveryKey(recoveryKey), nil
+}
+
+// PlanInit returns what Init would encrypt and delete at path, without
+// changing anything
+func PlanInit(path string, filter *FileFilter) (*TreePlan, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault path: %w", err)
+	}
+
+	plan, err := vault.New(absPath).PlanInitialize(filter.internal())
+	if err != nil {
+		return nil, err
+	}
+	return newTreePlan(plan), nil
+}
+
+// Path returns the vault's absolute path
+func (v *Vault) Path() string {
+	return v.path
+}
+
+// Unlock decrypts the vault's master key with a credential and returns a
+// session for working with the vault's contents
+// Returns an error wrapping ErrIncorrectPassword if the credential is wrong.
+// The caller must Close the session.
+func (v *Vault) Unlock(credential Credential) (*Session, error) {
+	return v.unlock(credential.credential())
+}
+
+// unlock returns a session for an internal credential
+func (v *Vault) unlock(credential vault.Credential) (*Session, error) {
+	session, err := v.v.Unlock(credential)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{vault: v, s: session}, nil
+}
+
+// KeyFingerprint identifies the vault's encrypted master key and salt
+// It changes when the vault is recreated or its password is changed, so it
+// can tell whether a key cached for the vault still applies.
+func (v *Vault) KeyFingerprint() (string, error) {
+	return v.v.KeyFingerprint()
+}
+
+// PlanAddDirectory returns what AddDirectory would encrypt and delete,
+// without changing anything
+func (v *Vault) PlanAddDirectory(dirPath string, filter *FileFilter) (*TreePlan, error) {
+	plan, err := v.v.PlanAddDirectory(dirPath, filter.internal())
+	if err != nil {
+		return nil, err
+	}
+	return newTreePlan(plan), nil
+}
+
+// Credential unlocks a vault's master key
+// The credentials are Password and RecoveryKey.
+type Credential interface {
+	credential() vault.Credential
+}
+
+// Password unlocks a vault with its password
+type Password string
+
+func (p Password) credential() vault.Credential {
+	return vault.Password(p)
+}
+
+// RecoveryKey unlocks a vault with the recovery key returned by Init
+type RecoveryKey []byte
+
+func (k RecoveryKey) credential() vault.Credential {
+	return vault.RecoveryKey(k)
+}
+
+// ParseRecoveryKey decodes a recovery key as shown by String, with or without
+// the dashes; other characters that are not hex digits are ignored too
+func ParseRecoveryKey(text string) (RecoveryKey, error) {
+	var hexKey strings.Builder
+	for _, c := range text {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			hexKey.WriteRune(c)
+		}
+	}
+
+	recoveryKey, err := crypto.DecodeRecoveryKeyHex(hexKey.String())
+	if err != nil {
+		return nil, err
+	}
+	return RecoveryKey(recoveryKey), nil
+}
+
+// String formats the recovery key for display, as groups of hex digits
+func (k RecoveryKey) String() string {
+	return crypto.FormatRecoveryKeyForDisplay(k)
+}
+
+// WipeWarning explains why overwriting files at the vault's location may
+// leave their old contents recoverable (for example on copy-on-write or flash
+// storage), or returns "" when nothing is known against it
+func (v *Vault) WipeWarning() string {
+	return storage.WipeWarning(v.path)
+}
@@ -0,0 +1,93 @@
+package vaultix_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Zayan-Mohamed/vaultix/pkg/vaultix"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	vaultDir := t.TempDir()
+	if _, _, err := vaultix.Init(vaultDir, "correct horse", nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if _, _, err := vaultix.Init(vaultDir, "correct horse", nil); !errors.Is(err, vaultix.ErrVaultExists) {
+		t.Errorf("second Init: err = %v, want ErrVaultExists", err)
+	}
+	if _, err := vaultix.Open(t.TempDir()); !errors.Is(err, vaultix.ErrVaultNotFound) {
+		t.Errorf("Open without a vault: err = %v, want ErrVaultNotFound", err)
+	}
+
+	v, err := vaultix.Open(vaultDir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := v.Unlock(vaultix.Password("wrong")); !errors.Is(err, vaultix.ErrIncorrectPassword) {
+		t.Errorf("Unlock with a wrong password: err = %v, want ErrIncorrectPassword", err)
+	}
+	session, err := v.Unlock(vaultix.Password("correct horse"))
+	if err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	defer session.Close()
+
+	// AddFile deletes the original
+	contents := []byte("DB_PASSWORD=hunter2\n")
+	source := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(source, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := session.AddFile(source); err != nil || version != 1 {
+		t.Fatalf("AddFile = %d, %v", version, err)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Errorf("original still exists after AddFile: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "out.env")
+	result, err := session.ExtractFile("app.env", dest, nil)
+	if err != nil {
+		t.Fatalf("ExtractFile: %v", err)
+	}
+	if len(result.Extracted) != 1 || result.Extracted[0] != "app.env" {
+		t.Errorf("ExtractFile extracted %q", result.Extracted)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, contents) {
+		t.Errorf("extracted %q, want %q", got, contents)
+	}
+
+	// Errors wrap the exported sentinels
+	if _, err := session.ExtractFile("missing.txt", dest, nil); !errors.Is(err, vaultix.ErrFileNotFound) {
+		t.Errorf("ExtractFile missing: err = %v, want ErrFileNotFound", err)
+	}
+	if err := session.RemoveFile("app", false); !errors.Is(err, vaultix.ErrFileNotFound) {
+		t.Errorf("RemoveFile by a partial name: err = %v, want ErrFileNotFound", err)
+	}
+	if _, err := session.RestoreFromTrash("app.env"); !errors.Is(err, vaultix.ErrNotInTrash) {
+		t.Errorf("RestoreFromTrash: err = %v, want ErrNotInTrash", err)
+	}
+	if _, err := session.AddReader("../escape", bytes.NewReader(nil)); !errors.Is(err, vaultix.ErrUnsafePath) {
+		t.Errorf("AddReader with an unsafe name: err = %v, want ErrUnsafePath", err)
+	}
+	if _, err := session.Prune(vaultix.PruneOptions{}); !errors.Is(err, vaultix.ErrNoRetention) {
+		t.Errorf("Prune without rules: err = %v, want ErrNoRetention", err)
+	}
+
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := session.ListFiles(); !errors.Is(err, vaultix.ErrSessionClosed) {
+		t.Errorf("ListFiles after Close: err = %v, want ErrSessionClosed", err)
+	}
+}